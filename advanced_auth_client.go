@@ -2,8 +2,12 @@ package httpclient
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"gopkg.in/h2non/gentleman.v2/plugin"
 	"gopkg.in/h2non/gentleman.v2/plugins/headers"
 )
@@ -16,10 +20,41 @@ type OidcTokenProvider interface {
 
 // RestClient interface
 type RestClient interface {
+	RestClientContext
 	Get(data any, plugins ...plugin.Plugin) error
 	Post(data any, plugins ...plugin.Plugin) (string, error)
 	Delete(plugins ...plugin.Plugin) error
 	Put(plugins ...plugin.Plugin) error
+	PutData(data any, plugins ...plugin.Plugin) (string, error)
+	DeleteData(data any, plugins ...plugin.Plugin) (string, error)
+	Patch(data any, plugins ...plugin.Plugin) error
+	Head(plugins ...plugin.Plugin) (http.Header, error)
+	Options(plugins ...plugin.Plugin) (http.Header, error)
+	Download(w io.Writer, plugins ...plugin.Plugin) (int64, error)
+	DownloadFile(path string, plugins ...plugin.Plugin) (int64, error)
+	PostBody(in, out any, plugins ...plugin.Plugin) (string, error)
+	PutBody(in, out any, plugins ...plugin.Plugin) (string, error)
+	PatchBody(in, out any, plugins ...plugin.Plugin) error
+}
+
+// RestClientContext interface provides the RestClient methods bound to a caller context
+type RestClientContext interface {
+	Do(ctx context.Context, method string, plugins ...plugin.Plugin) (*Response, error)
+	Stream(ctx context.Context, method string, handle func(*Response) error, plugins ...plugin.Plugin) error
+	GetCtx(ctx context.Context, data any, plugins ...plugin.Plugin) error
+	PostCtx(ctx context.Context, data any, plugins ...plugin.Plugin) (string, error)
+	DeleteCtx(ctx context.Context, plugins ...plugin.Plugin) error
+	PutCtx(ctx context.Context, plugins ...plugin.Plugin) error
+	PutDataCtx(ctx context.Context, data any, plugins ...plugin.Plugin) (string, error)
+	DeleteDataCtx(ctx context.Context, data any, plugins ...plugin.Plugin) (string, error)
+	PatchCtx(ctx context.Context, data any, plugins ...plugin.Plugin) error
+	HeadCtx(ctx context.Context, plugins ...plugin.Plugin) (http.Header, error)
+	OptionsCtx(ctx context.Context, plugins ...plugin.Plugin) (http.Header, error)
+	DownloadCtx(ctx context.Context, w io.Writer, plugins ...plugin.Plugin) (int64, error)
+	DownloadFileCtx(ctx context.Context, path string, plugins ...plugin.Plugin) (int64, error)
+	PostBodyCtx(ctx context.Context, in, out any, plugins ...plugin.Plugin) (string, error)
+	PutBodyCtx(ctx context.Context, in, out any, plugins ...plugin.Plugin) (string, error)
+	PatchBodyCtx(ctx context.Context, in, out any, plugins ...plugin.Plugin) error
 }
 
 // MultiRealmTokenClient struct
@@ -27,11 +62,18 @@ type MultiRealmTokenClient struct {
 	client        *Client
 	tokenProvider OidcTokenProvider
 	realm         string
+	realmClients  *realmClients
+}
+
+// realmClients holds the realm-scoped clients when each realm has its own circuit breaker
+type realmClients struct {
+	mutex   sync.Mutex
+	clients map[string]*Client
 }
 
 // NewMultiRealmTokenClient creates a MultiRealmTokenClient instance
-func NewMultiRealmTokenClient(addrAPI string, reqTimeout time.Duration, tokenProvider OidcTokenProvider) (*MultiRealmTokenClient, error) {
-	var client, err = New(addrAPI, reqTimeout)
+func NewMultiRealmTokenClient(addrAPI string, reqTimeout time.Duration, tokenProvider OidcTokenProvider, opts ...Option) (*MultiRealmTokenClient, error) {
+	var client, err = NewWithOptions(addrAPI, reqTimeout, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -39,60 +81,260 @@ func NewMultiRealmTokenClient(addrAPI string, reqTimeout time.Duration, tokenPro
 		client:        client,
 		tokenProvider: tokenProvider,
 		realm:         "",
+		realmClients:  &realmClients{clients: map[string]*Client{}},
 	}, nil
 }
 
 // ForRealm returns a realm-specific RestClient
 func (mrtc *MultiRealmTokenClient) ForRealm(realm string) RestClient {
 	return &MultiRealmTokenClient{
-		client:        mrtc.client,
+		client:        mrtc.clientForRealm(realm),
 		tokenProvider: mrtc.tokenProvider,
 		realm:         realm,
+		realmClients:  mrtc.realmClients,
+	}
+}
+
+// clientForRealm returns the client used for realm. When a circuit breaker is configured, each realm gets its own
+func (mrtc *MultiRealmTokenClient) clientForRealm(realm string) *Client {
+	if mrtc.client.breakerSettings == nil {
+		return mrtc.client
+	}
+
+	mrtc.realmClients.mutex.Lock()
+	defer mrtc.realmClients.mutex.Unlock()
+
+	var client, ok = mrtc.realmClients.clients[realm]
+	if !ok {
+		client = mrtc.client.withCircuitBreaker(NewCircuitBreaker(*mrtc.client.breakerSettings))
+		mrtc.realmClients.clients[realm] = client
 	}
+	return client
 }
 
-func (mrtc *MultiRealmTokenClient) withRealmAuth(next func(pluginsWithAuth ...plugin.Plugin) (string, error), plugins ...plugin.Plugin) (string, error) {
+func (mrtc *MultiRealmTokenClient) withRealmAuth(ctx context.Context, next func(pluginsWithAuth ...plugin.Plugin) error, plugins ...plugin.Plugin) error {
 	var token string
 	var err error
 	if mrtc.realm != "" {
-		token, err = mrtc.tokenProvider.ProvideTokenForRealm(context.Background(), mrtc.realm)
+		token, err = mrtc.tokenProvider.ProvideTokenForRealm(ctx, mrtc.realm)
 	} else {
-		token, err = mrtc.tokenProvider.ProvideToken(context.Background())
+		token, err = mrtc.tokenProvider.ProvideToken(ctx)
 	}
 	if err != nil {
-		return "", err
+		return err
+	}
+	// The token provider may have ignored ctx: do not issue the request if it has been cancelled meanwhile
+	if err = ctx.Err(); err != nil {
+		return errors.Wrap(&canceledError{err: err}, MsgErrCanceled+"."+PrmTokenMsg)
 	}
 	plugins = append(plugins, headers.Set("Authorization", "Bearer "+token))
 	return next(plugins...)
 }
 
+func (mrtc *MultiRealmTokenClient) withRealmAuthLocation(ctx context.Context, next func(pluginsWithAuth ...plugin.Plugin) (string, error), plugins ...plugin.Plugin) (string, error) {
+	var location string
+	var err = mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		var err error
+		location, err = next(pluginsWithAuth...)
+		return err
+	}, plugins...)
+	return location, err
+}
+
+// Do sends a HTTP request using the given method and returns the response with its body fully read
+func (mrtc *MultiRealmTokenClient) Do(ctx context.Context, method string, plugins ...plugin.Plugin) (*Response, error) {
+	var response *Response
+	var err = mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		var err error
+		response, err = mrtc.client.Do(ctx, method, pluginsWithAuth...)
+		return err
+	}, plugins...)
+	return response, err
+}
+
+// Stream sends a HTTP request using the given method and lets handle read the response body while it is received
+func (mrtc *MultiRealmTokenClient) Stream(ctx context.Context, method string, handle func(*Response) error, plugins ...plugin.Plugin) error {
+	return mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		return mrtc.client.Stream(ctx, method, handle, pluginsWithAuth...)
+	}, plugins...)
+}
+
 // Get is a HTTP GET method.
 func (mrtc *MultiRealmTokenClient) Get(data any, plugins ...plugin.Plugin) error {
-	var _, err = mrtc.withRealmAuth(func(pluginsWithAuth ...plugin.Plugin) (string, error) {
-		return "", mrtc.client.Get(data, pluginsWithAuth...)
+	return mrtc.GetCtx(context.Background(), data, plugins...)
+}
+
+// GetCtx is a HTTP GET method bound to the context ctx.
+func (mrtc *MultiRealmTokenClient) GetCtx(ctx context.Context, data any, plugins ...plugin.Plugin) error {
+	return mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		return mrtc.client.GetCtx(ctx, data, pluginsWithAuth...)
 	}, plugins...)
-	return err
 }
 
 // Post is a HTTP POST method
 func (mrtc *MultiRealmTokenClient) Post(data any, plugins ...plugin.Plugin) (string, error) {
-	return mrtc.withRealmAuth(func(pluginsWithAuth ...plugin.Plugin) (string, error) {
-		return mrtc.client.Post(data, pluginsWithAuth...)
+	return mrtc.PostCtx(context.Background(), data, plugins...)
+}
+
+// PostCtx is a HTTP POST method bound to the context ctx.
+func (mrtc *MultiRealmTokenClient) PostCtx(ctx context.Context, data any, plugins ...plugin.Plugin) (string, error) {
+	return mrtc.withRealmAuthLocation(ctx, func(pluginsWithAuth ...plugin.Plugin) (string, error) {
+		return mrtc.client.PostCtx(ctx, data, pluginsWithAuth...)
 	}, plugins...)
 }
 
 // Delete is a HTTP DELETE method
 func (mrtc *MultiRealmTokenClient) Delete(plugins ...plugin.Plugin) error {
-	var _, err = mrtc.withRealmAuth(func(pluginsWithAuth ...plugin.Plugin) (string, error) {
-		return "", mrtc.client.Delete(pluginsWithAuth...)
+	return mrtc.DeleteCtx(context.Background(), plugins...)
+}
+
+// DeleteCtx is a HTTP DELETE method bound to the context ctx.
+func (mrtc *MultiRealmTokenClient) DeleteCtx(ctx context.Context, plugins ...plugin.Plugin) error {
+	return mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		return mrtc.client.DeleteCtx(ctx, pluginsWithAuth...)
+	}, plugins...)
+}
+
+// DeleteData is a HTTP DELETE method which reads the response content into data. It returns the Location header
+func (mrtc *MultiRealmTokenClient) DeleteData(data any, plugins ...plugin.Plugin) (string, error) {
+	return mrtc.DeleteDataCtx(context.Background(), data, plugins...)
+}
+
+// DeleteDataCtx is a HTTP DELETE method bound to the context ctx which reads the response content into data. It returns the Location header
+func (mrtc *MultiRealmTokenClient) DeleteDataCtx(ctx context.Context, data any, plugins ...plugin.Plugin) (string, error) {
+	return mrtc.withRealmAuthLocation(ctx, func(pluginsWithAuth ...plugin.Plugin) (string, error) {
+		return mrtc.client.DeleteDataCtx(ctx, data, pluginsWithAuth...)
 	}, plugins...)
-	return err
 }
 
 // Put is a HTTP PUT method
 func (mrtc *MultiRealmTokenClient) Put(plugins ...plugin.Plugin) error {
-	var _, err = mrtc.withRealmAuth(func(pluginsWithAuth ...plugin.Plugin) (string, error) {
-		return "", mrtc.client.Put(pluginsWithAuth...)
+	return mrtc.PutCtx(context.Background(), plugins...)
+}
+
+// PutCtx is a HTTP PUT method bound to the context ctx.
+func (mrtc *MultiRealmTokenClient) PutCtx(ctx context.Context, plugins ...plugin.Plugin) error {
+	return mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		return mrtc.client.PutCtx(ctx, pluginsWithAuth...)
 	}, plugins...)
-	return err
+}
+
+// PutData is a HTTP PUT method which reads the response content into data. It returns the Location header
+func (mrtc *MultiRealmTokenClient) PutData(data any, plugins ...plugin.Plugin) (string, error) {
+	return mrtc.PutDataCtx(context.Background(), data, plugins...)
+}
+
+// PutDataCtx is a HTTP PUT method bound to the context ctx which reads the response content into data. It returns the Location header
+func (mrtc *MultiRealmTokenClient) PutDataCtx(ctx context.Context, data any, plugins ...plugin.Plugin) (string, error) {
+	return mrtc.withRealmAuthLocation(ctx, func(pluginsWithAuth ...plugin.Plugin) (string, error) {
+		return mrtc.client.PutDataCtx(ctx, data, pluginsWithAuth...)
+	}, plugins...)
+}
+
+// Patch is a HTTP PATCH method
+func (mrtc *MultiRealmTokenClient) Patch(data any, plugins ...plugin.Plugin) error {
+	return mrtc.PatchCtx(context.Background(), data, plugins...)
+}
+
+// PatchCtx is a HTTP PATCH method bound to the context ctx.
+func (mrtc *MultiRealmTokenClient) PatchCtx(ctx context.Context, data any, plugins ...plugin.Plugin) error {
+	return mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		return mrtc.client.PatchCtx(ctx, data, pluginsWithAuth...)
+	}, plugins...)
+}
+
+// Head is a HTTP HEAD method. It returns the response headers
+func (mrtc *MultiRealmTokenClient) Head(plugins ...plugin.Plugin) (http.Header, error) {
+	return mrtc.HeadCtx(context.Background(), plugins...)
+}
+
+// HeadCtx is a HTTP HEAD method bound to the context ctx. It returns the response headers
+func (mrtc *MultiRealmTokenClient) HeadCtx(ctx context.Context, plugins ...plugin.Plugin) (http.Header, error) {
+	var header http.Header
+	var err = mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		var err error
+		header, err = mrtc.client.HeadCtx(ctx, pluginsWithAuth...)
+		return err
+	}, plugins...)
+	return header, err
+}
+
+// Options is a HTTP OPTIONS method. It returns the response headers
+func (mrtc *MultiRealmTokenClient) Options(plugins ...plugin.Plugin) (http.Header, error) {
+	return mrtc.OptionsCtx(context.Background(), plugins...)
+}
+
+// OptionsCtx is a HTTP OPTIONS method bound to the context ctx. It returns the response headers
+func (mrtc *MultiRealmTokenClient) OptionsCtx(ctx context.Context, plugins ...plugin.Plugin) (http.Header, error) {
+	var header http.Header
+	var err = mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		var err error
+		header, err = mrtc.client.OptionsCtx(ctx, pluginsWithAuth...)
+		return err
+	}, plugins...)
+	return header, err
+}
+
+// PostBody is a HTTP POST method sending in as request body and reading the response content into out. It returns the Location header
+func (mrtc *MultiRealmTokenClient) PostBody(in, out any, plugins ...plugin.Plugin) (string, error) {
+	return mrtc.PostBodyCtx(context.Background(), in, out, plugins...)
+}
+
+// PostBodyCtx is a HTTP POST method bound to the context ctx sending in as request body and reading the response content into out. It returns the Location header
+func (mrtc *MultiRealmTokenClient) PostBodyCtx(ctx context.Context, in, out any, plugins ...plugin.Plugin) (string, error) {
+	return mrtc.withRealmAuthLocation(ctx, func(pluginsWithAuth ...plugin.Plugin) (string, error) {
+		return mrtc.client.PostBodyCtx(ctx, in, out, pluginsWithAuth...)
+	}, plugins...)
+}
+
+// PutBody is a HTTP PUT method sending in as request body and reading the response content into out. It returns the Location header
+func (mrtc *MultiRealmTokenClient) PutBody(in, out any, plugins ...plugin.Plugin) (string, error) {
+	return mrtc.PutBodyCtx(context.Background(), in, out, plugins...)
+}
+
+// PutBodyCtx is a HTTP PUT method bound to the context ctx sending in as request body and reading the response content into out. It returns the Location header
+func (mrtc *MultiRealmTokenClient) PutBodyCtx(ctx context.Context, in, out any, plugins ...plugin.Plugin) (string, error) {
+	return mrtc.withRealmAuthLocation(ctx, func(pluginsWithAuth ...plugin.Plugin) (string, error) {
+		return mrtc.client.PutBodyCtx(ctx, in, out, pluginsWithAuth...)
+	}, plugins...)
+}
+
+// PatchBody is a HTTP PATCH method sending in as request body and reading the response content into out
+func (mrtc *MultiRealmTokenClient) PatchBody(in, out any, plugins ...plugin.Plugin) error {
+	return mrtc.PatchBodyCtx(context.Background(), in, out, plugins...)
+}
+
+// PatchBodyCtx is a HTTP PATCH method bound to the context ctx sending in as request body and reading the response content into out
+func (mrtc *MultiRealmTokenClient) PatchBodyCtx(ctx context.Context, in, out any, plugins ...plugin.Plugin) error {
+	return mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		return mrtc.client.PatchBodyCtx(ctx, in, out, pluginsWithAuth...)
+	}, plugins...)
+}
+
+// Download is a HTTP GET method writing the response body to w while it is received. It returns the number of bytes written
+func (mrtc *MultiRealmTokenClient) Download(w io.Writer, plugins ...plugin.Plugin) (int64, error) {
+	return mrtc.DownloadCtx(context.Background(), w, plugins...)
+}
+
+// DownloadCtx is a HTTP GET method bound to the context ctx writing the response body to w while it is received. It returns the number of bytes written
+func (mrtc *MultiRealmTokenClient) DownloadCtx(ctx context.Context, w io.Writer, plugins ...plugin.Plugin) (int64, error) {
+	var written int64
+	var err = mrtc.withRealmAuth(ctx, func(pluginsWithAuth ...plugin.Plugin) error {
+		var err error
+		written, err = mrtc.client.DownloadCtx(ctx, w, pluginsWithAuth...)
+		return err
+	}, plugins...)
+	return written, err
+}
+
+// DownloadFile is a HTTP GET method writing the response body to the file at path. It returns the number of bytes written
+func (mrtc *MultiRealmTokenClient) DownloadFile(path string, plugins ...plugin.Plugin) (int64, error) {
+	return mrtc.DownloadFileCtx(context.Background(), path, plugins...)
+}
+
+// DownloadFileCtx is a HTTP GET method bound to the context ctx writing the response body to the file at path. It returns the number of bytes written
+func (mrtc *MultiRealmTokenClient) DownloadFileCtx(ctx context.Context, path string, plugins ...plugin.Plugin) (int64, error) {
+	return downloadFile(path, func(w io.Writer) (int64, error) {
+		return mrtc.DownloadCtx(ctx, w, plugins...)
+	})
 }
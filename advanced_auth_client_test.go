@@ -1,13 +1,20 @@
 package httpclient
 
 import (
+	"context"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
 )
 
 func TestNewMultiRealmTokenClient(t *testing.T) {
@@ -74,4 +81,174 @@ func TestNewMultiRealmTokenClient(t *testing.T) {
 			assert.NotEqual(t, tokenError, err)
 		})
 	})
+	t.Run("PUT with data", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("", tokenError)
+			var _, err = client.ForRealm(realm).PutData(nil)
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("token-for-"+realm, nil)
+			var _, err = client.ForRealm(realm).PutData(nil)
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+	t.Run("DELETE with data", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("", tokenError)
+			var _, err = client.ForRealm(realm).DeleteData(nil)
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("token-for-"+realm, nil)
+			var _, err = client.ForRealm(realm).DeleteData(nil)
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+	t.Run("PATCH", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("", tokenError)
+			var err = client.ForRealm(realm).Patch(nil)
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("token-for-"+realm, nil)
+			var err = client.ForRealm(realm).Patch(nil)
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+	t.Run("HEAD", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("", tokenError)
+			var _, err = client.ForRealm(realm).Head()
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("token-for-"+realm, nil)
+			var _, err = client.ForRealm(realm).Head()
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+	t.Run("OPTIONS", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return("", tokenError)
+			var _, err = client.Options()
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return("default-token", nil)
+			var _, err = client.Options()
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+	t.Run("Download", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("", tokenError)
+			var _, err = client.ForRealm(realm).Download(io.Discard)
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("token-for-"+realm, nil)
+			var _, err = client.ForRealm(realm).Download(io.Discard)
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+	t.Run("Download file", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return("", tokenError)
+			var _, err = client.DownloadFile(filepath.Join(t.TempDir(), "export"))
+			assert.Equal(t, tokenError, err)
+		})
+	})
+	t.Run("POST body", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("", tokenError)
+			var _, err = client.ForRealm(realm).PostBody(nil, nil)
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("token-for-"+realm, nil)
+			var _, err = client.ForRealm(realm).PostBody(nil, nil)
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+	t.Run("PUT body", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return("", tokenError)
+			var _, err = client.PutBody(nil, nil)
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return("default-token", nil)
+			var _, err = client.PutBody(nil, nil)
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+	t.Run("PATCH body", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("", tokenError)
+			var err = client.ForRealm(realm).PatchBody(nil, nil)
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("token-for-"+realm, nil)
+			var err = client.ForRealm(realm).PatchBody(nil, nil)
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+	t.Run("Stream", func(t *testing.T) {
+		t.Run("can't get token", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("", tokenError)
+			var err = client.ForRealm(realm).Stream(context.Background(), http.MethodGet, discardContent)
+			assert.Equal(t, tokenError, err)
+		})
+		t.Run("success", func(t *testing.T) {
+			mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).Return("token-for-"+realm, nil)
+			var err = client.ForRealm(realm).Stream(context.Background(), http.MethodGet, discardContent)
+			assert.NotEqual(t, tokenError, err)
+		})
+	})
+}
+
+func TestMultiRealmTokenClientContext(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockTokenProvider = mock.NewOidcTokenProvider(mockCtrl)
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var realm = "my-realm"
+
+	r := mux.NewRouter()
+	r.Handle("/sample", mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, err = NewMultiRealmTokenClient(ts.URL, time.Minute, mockTokenProvider)
+	assert.Nil(t, err)
+
+	type ctxKey struct{}
+	var ctx = context.WithValue(context.Background(), ctxKey{}, "trace-id")
+
+	t.Run("Caller context reaches the token provider", func(t *testing.T) {
+		mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), realm).DoAndReturn(func(ctx context.Context, _ string) (string, error) {
+			assert.Equal(t, "trace-id", ctx.Value(ctxKey{}))
+			return "token-for-" + realm, nil
+		})
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer token-for-"+realm, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusNoContent)
+		})
+		var err = client.ForRealm(realm).DeleteCtx(ctx, url.Path("/sample"))
+		assert.Nil(t, err)
+	})
+	t.Run("Cancelled during token acquisition", func(t *testing.T) {
+		var ctx, cancel = context.WithCancel(ctx)
+		mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).DoAndReturn(func(ctx context.Context) (string, error) {
+			cancel()
+			return "default-token", nil
+		})
+		var err = client.GetCtx(ctx, nil, url.Path("/sample"))
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
 }
@@ -0,0 +1,76 @@
+package httpclient
+
+import (
+	"io"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// maxBodySizeKey is the key of the request context store holding the maximum body size set by MaxBodySize
+type maxBodySizeKey struct{}
+
+// WithMaxBodySize limits the number of bytes the client reads from a response body. Reading more fails with ErrBodyTooLarge.
+// The limit applies to the body once decompressed by the transport. A non positive value disables the limit
+func WithMaxBodySize(maxBytes int64) Option {
+	return func(c *Client) {
+		c.maxBodySize = maxBytes
+	}
+}
+
+// MaxBodySize overrides the maximum response body size of the client for a single request. A non positive value disables the limit
+func MaxBodySize(maxBytes int64) plugin.Plugin {
+	return plugin.NewRequestPlugin(func(ctx *context.Context, h context.Handler) {
+		ctx.Set(maxBodySizeKey{}, maxBytes)
+		h.Next(ctx)
+	})
+}
+
+// maxBodySizeOf returns the maximum response body size applicable to the request having the context ctx
+func (c *Client) maxBodySizeOf(ctx *context.Context) int64 {
+	if maxBytes, ok := ctx.GetOk(maxBodySizeKey{}); ok {
+		return maxBytes.(int64)
+	}
+	return c.maxBodySize
+}
+
+// limitedBody is a response body failing with ErrBodyTooLarge when more than remaining bytes are read
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	err       error
+}
+
+func newLimitedBody(body io.ReadCloser, maxBytes int64) io.ReadCloser {
+	if maxBytes <= 0 || body == nil {
+		return body
+	}
+	return &limitedBody{body: body, remaining: maxBytes}
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// Reads one byte more than allowed to detect bodies exceeding the limit
+	if int64(len(p))-1 > l.remaining {
+		p = p[:l.remaining+1]
+	}
+	var n, err = l.body.Read(p)
+	if int64(n) <= l.remaining {
+		l.remaining -= int64(n)
+		l.err = err
+		return n, err
+	}
+	n = int(l.remaining)
+	l.remaining = 0
+	l.err = ErrBodyTooLarge
+	return n, l.err
+}
+
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
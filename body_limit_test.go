@@ -0,0 +1,133 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithMaxBodySize(100))
+
+	var respondWith = func(status int, body string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}
+	}
+
+	t.Run("Body within the limit", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, strings.Repeat("a", 100)))
+		var resp string
+		assert.Nil(t, client.Get(&resp, url.Path(path)))
+		assert.Len(t, resp, 100)
+	})
+	t.Run("Body too large", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, strings.Repeat("a", 101)))
+		var resp string
+		var err = client.Get(&resp, url.Path(path))
+		assert.True(t, errors.Is(err, ErrBodyTooLarge))
+		assert.Equal(t, "", resp)
+	})
+	t.Run("Do", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, strings.Repeat("a", 1000)))
+		var _, err = client.Do(context.Background(), http.MethodGet, url.Path(path))
+		assert.True(t, errors.Is(err, ErrBodyTooLarge))
+	})
+	t.Run("Body read in memory by Stream", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, strings.Repeat("a", 1000)))
+		var content []byte
+		var err = client.Stream(context.Background(), http.MethodGet, func(resp *Response) error {
+			content = resp.Bytes()
+			return nil
+		}, url.Path(path))
+		assert.True(t, errors.Is(err, ErrBodyTooLarge))
+		assert.Nil(t, content)
+	})
+	t.Run("Body of unknown media type", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(strings.Repeat("a", 1000)))
+		})
+		var resp string
+		var err = client.Get(&resp, url.Path(path))
+		assert.True(t, errors.Is(err, ErrBodyTooLarge))
+	})
+	t.Run("Limit overridden for a request", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, strings.Repeat("a", 1000))).Times(2)
+		var resp string
+		assert.Nil(t, client.Get(&resp, url.Path(path), MaxBodySize(1000)))
+		assert.Len(t, resp, 1000)
+		assert.Nil(t, client.Get(&resp, url.Path(path), MaxBodySize(0)))
+	})
+	t.Run("Error body is cut at the limit", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusInternalServerError, strings.Repeat("a", 1000)))
+		var err = client.Get(nil, url.Path(path))
+		var httpErr HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, strings.Repeat("a", 100), httpErr.Message)
+	})
+	t.Run("Limit applies to the decompressed body", func(t *testing.T) {
+		var compressed bytes.Buffer
+		var writer = gzip.NewWriter(&compressed)
+		writer.Write(bytes.Repeat([]byte("a"), 10<<20))
+		writer.Close()
+		assert.True(t, compressed.Len() < 100<<10)
+
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+		})
+		var resp string
+		var err = client.Get(&resp, url.Path(path), MaxBodySize(1<<20))
+		assert.True(t, errors.Is(err, ErrBodyTooLarge))
+	})
+}
+
+func TestLimitedBody(t *testing.T) {
+	var body = newLimitedBody(io.NopCloser(strings.NewReader("0123456789")), 4)
+	var buffer = make([]byte, 3)
+
+	var n, err = body.Read(buffer)
+	assert.Equal(t, 3, n)
+	assert.Nil(t, err)
+
+	n, err = body.Read(buffer)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, ErrBodyTooLarge, err)
+
+	n, err = body.Read(buffer)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, ErrBodyTooLarge, err)
+
+	var content, _ = io.ReadAll(newLimitedBody(io.NopCloser(strings.NewReader("0123")), 4))
+	assert.Equal(t, "0123", string(content))
+}
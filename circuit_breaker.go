@@ -0,0 +1,210 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int
+
+// Circuit breaker states
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of trial requests through
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerSettings configures a CircuitBreaker
+type CircuitBreakerSettings struct {
+	// FailureRatio is the proportion of failed requests within a window above which the circuit opens
+	FailureRatio float64
+	// MinRequests is the number of requests needed within a window before the failure ratio is evaluated
+	MinRequests int
+	// Window is the duration after which request counts are reset while the circuit is closed
+	Window time.Duration
+	// Cooldown is how long the circuit stays open before letting trial requests through
+	Cooldown time.Duration
+	// HalfOpenRequests is the number of successful trial requests needed to close the circuit again
+	HalfOpenRequests int
+}
+
+// DefaultCircuitBreakerSettings returns settings opening the circuit when half of at least 10 requests fail within 10 seconds
+func DefaultCircuitBreakerSettings() CircuitBreakerSettings {
+	return CircuitBreakerSettings{
+		FailureRatio:     0.5,
+		MinRequests:      10,
+		Window:           10 * time.Second,
+		Cooldown:         30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+// WithCircuitBreaker protects the client with a circuit breaker.
+// A MultiRealmTokenClient created with this option uses a distinct circuit breaker for each realm
+func WithCircuitBreaker(settings CircuitBreakerSettings) Option {
+	return func(c *Client) {
+		c.breakerSettings = &settings
+		c.breaker = NewCircuitBreaker(settings)
+	}
+}
+
+// CircuitBreaker stops sending requests to an upstream service which keeps failing
+type CircuitBreaker struct {
+	settings CircuitBreakerSettings
+	now      func() time.Time
+
+	mutex       sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	trials      int
+	successes   int
+}
+
+type circuitOutcome int
+
+const (
+	outcomeSuccess circuitOutcome = iota
+	outcomeFailure
+	// outcomeIgnored is used when the request did not reach the upstream service
+	outcomeIgnored
+)
+
+// NewCircuitBreaker creates a closed circuit breaker. A non positive FailureRatio or MinRequests is replaced by its default value
+func NewCircuitBreaker(settings CircuitBreakerSettings) *CircuitBreaker {
+	var defaults = DefaultCircuitBreakerSettings()
+	if settings.FailureRatio <= 0 {
+		settings.FailureRatio = defaults.FailureRatio
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = defaults.MinRequests
+	}
+	return &CircuitBreaker{
+		settings: settings,
+		now:      time.Now,
+		state:    CircuitClosed,
+	}
+}
+
+// State returns the current state of the circuit breaker
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.refresh(cb.now())
+	return cb.state
+}
+
+// refresh moves an open circuit to half-open once the cooldown elapsed. The mutex must be held
+func (cb *CircuitBreaker) refresh(now time.Time) {
+	if cb.state == CircuitOpen && !now.Before(cb.openedAt.Add(cb.settings.Cooldown)) {
+		cb.state = CircuitHalfOpen
+		cb.trials = 0
+		cb.successes = 0
+	}
+}
+
+// before is called before sending a request and returns ErrCircuitOpen if the request must not be sent.
+// Each successful call must be followed by a call to after
+func (cb *CircuitBreaker) before() error {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	var now = cb.now()
+	cb.refresh(now)
+	switch cb.state {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if cb.trials >= max(cb.settings.HalfOpenRequests, 1) {
+			return ErrCircuitOpen
+		}
+		cb.trials++
+	default:
+		if cb.settings.Window > 0 && !now.Before(cb.windowStart.Add(cb.settings.Window)) {
+			cb.windowStart = now
+			cb.requests = 0
+			cb.failures = 0
+		}
+	}
+	return nil
+}
+
+// after records the outcome of a request allowed by before
+func (cb *CircuitBreaker) after(outcome circuitOutcome) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	var now = cb.now()
+	switch cb.state {
+	case CircuitHalfOpen:
+		switch outcome {
+		case outcomeFailure:
+			cb.open(now)
+		case outcomeSuccess:
+			cb.successes++
+			if cb.successes >= max(cb.settings.HalfOpenRequests, 1) {
+				cb.state = CircuitClosed
+				cb.windowStart = now
+				cb.requests = 0
+				cb.failures = 0
+			}
+		default:
+			cb.trials--
+		}
+	case CircuitClosed:
+		if outcome == outcomeIgnored {
+			return
+		}
+		cb.requests++
+		if outcome == outcomeFailure {
+			cb.failures++
+		}
+		if cb.failures > 0 && cb.requests >= cb.settings.MinRequests && float64(cb.failures) >= cb.settings.FailureRatio*float64(cb.requests) {
+			cb.open(now)
+		}
+	}
+}
+
+// circuitOutcomeOf tells how the result of a request affects the circuit breaker: only network failures and server errors count as failures
+func circuitOutcomeOf(err error) circuitOutcome {
+	var tErr *transportError
+	var httpErr HTTPError
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case errors.As(err, &tErr):
+		return outcomeFailure
+	case errors.As(err, &httpErr):
+		if httpErr.IsErrorFromServer() {
+			return outcomeFailure
+		}
+		return outcomeSuccess
+	default:
+		return outcomeIgnored
+	}
+}
+
+func (cb *CircuitBreaker) open(now time.Time) {
+	cb.state = CircuitOpen
+	cb.openedAt = now
+}
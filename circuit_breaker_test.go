@@ -0,0 +1,148 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+func testCircuitBreakerSettings() CircuitBreakerSettings {
+	return CircuitBreakerSettings{
+		FailureRatio:     0.5,
+		MinRequests:      2,
+		Window:           time.Minute,
+		Cooldown:         time.Minute,
+		HalfOpenRequests: 1,
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var now = time.Now()
+	var cb = NewCircuitBreaker(testCircuitBreakerSettings())
+	cb.now = func() time.Time { return now }
+
+	t.Run("Closed circuit lets requests through", func(t *testing.T) {
+		assert.Nil(t, cb.before())
+		cb.after(outcomeSuccess)
+		assert.Nil(t, cb.before())
+		cb.after(outcomeIgnored)
+		assert.Equal(t, CircuitClosed, cb.State())
+	})
+	t.Run("Opens when the failure ratio is reached", func(t *testing.T) {
+		assert.Nil(t, cb.before())
+		cb.after(outcomeFailure)
+		assert.Equal(t, CircuitOpen, cb.State())
+		assert.Equal(t, ErrCircuitOpen, cb.before())
+	})
+	t.Run("Half-open after cooldown", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		assert.Equal(t, CircuitHalfOpen, cb.State())
+		assert.Nil(t, cb.before())
+		// Only one trial request at a time
+		assert.Equal(t, ErrCircuitOpen, cb.before())
+	})
+	t.Run("Failed trial opens the circuit again", func(t *testing.T) {
+		cb.after(outcomeFailure)
+		assert.Equal(t, CircuitOpen, cb.State())
+	})
+	t.Run("Successful trial closes the circuit", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		assert.Nil(t, cb.before())
+		cb.after(outcomeSuccess)
+		assert.Equal(t, CircuitClosed, cb.State())
+	})
+	t.Run("Counts are reset at the end of the window", func(t *testing.T) {
+		assert.Nil(t, cb.before())
+		cb.after(outcomeFailure)
+		now = now.Add(time.Minute)
+		assert.Nil(t, cb.before())
+		cb.after(outcomeSuccess)
+		assert.Equal(t, CircuitClosed, cb.State())
+	})
+	t.Run("State names", func(t *testing.T) {
+		assert.Equal(t, "closed", CircuitClosed.String())
+		assert.Equal(t, "open", CircuitOpen.String())
+		assert.Equal(t, "half-open", CircuitHalfOpen.String())
+		assert.Equal(t, "unknown", CircuitState(42).String())
+	})
+}
+
+func TestCircuitBreakerSuccesses(t *testing.T) {
+	for _, settings := range []CircuitBreakerSettings{testCircuitBreakerSettings(), {MinRequests: 3, Window: time.Minute, Cooldown: time.Minute}} {
+		var cb = NewCircuitBreaker(settings)
+		for i := 0; i < 20; i++ {
+			assert.Nil(t, cb.before())
+			cb.after(outcomeSuccess)
+		}
+		assert.Equal(t, CircuitClosed, cb.State())
+	}
+}
+
+func TestCircuitBreakerDefaultSettings(t *testing.T) {
+	var cb = NewCircuitBreaker(CircuitBreakerSettings{Window: time.Minute, Cooldown: time.Minute})
+	assert.Equal(t, DefaultCircuitBreakerSettings().FailureRatio, cb.settings.FailureRatio)
+	assert.Equal(t, DefaultCircuitBreakerSettings().MinRequests, cb.settings.MinRequests)
+	assert.Equal(t, time.Minute, cb.settings.Window)
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var mockTokenProvider = mock.NewOidcTokenProvider(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var serverError = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	t.Run("Client", func(t *testing.T) {
+		var client, _ = NewWithOptions(ts.URL, time.Minute, WithCircuitBreaker(testCircuitBreakerSettings()))
+
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(serverError).Times(2)
+		assert.NotNil(t, client.Get(nil, url.Path(path)))
+		assert.NotNil(t, client.Get(nil, url.Path(path)))
+
+		var err = client.Get(nil, url.Path(path))
+		assert.True(t, errors.Is(err, ErrCircuitOpen))
+	})
+	t.Run("Client errors do not open the circuit", func(t *testing.T) {
+		var client, _ = NewWithOptions(ts.URL, time.Minute, WithCircuitBreaker(testCircuitBreakerSettings()))
+
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}).Times(3)
+		for range 3 {
+			var httpErr HTTPError
+			assert.True(t, errors.As(client.Get(nil, url.Path(path)), &httpErr))
+		}
+	})
+	t.Run("Circuit breaker per realm", func(t *testing.T) {
+		var client, _ = NewMultiRealmTokenClient(ts.URL, time.Minute, mockTokenProvider, WithCircuitBreaker(testCircuitBreakerSettings()))
+
+		mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), gomock.Any()).Return("token", nil).AnyTimes()
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(serverError).Times(3)
+		assert.NotNil(t, client.ForRealm("realm-a").Delete(url.Path(path)))
+		assert.NotNil(t, client.ForRealm("realm-a").Delete(url.Path(path)))
+		assert.Equal(t, ErrCircuitOpen, client.ForRealm("realm-a").Delete(url.Path(path)))
+
+		// Other realms are not affected
+		var err = client.ForRealm("realm-b").Delete(url.Path(path))
+		assert.False(t, errors.Is(err, ErrCircuitOpen))
+	})
+}
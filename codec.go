@@ -0,0 +1,311 @@
+package httpclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Media types of the request bodies encoded by default
+const (
+	MediaTypeJSON           = "application/json"
+	MediaTypeFormURLEncoded = "application/x-www-form-urlencoded"
+)
+
+// Decoder reads a response body into data
+type Decoder func(body io.Reader, data any) error
+
+// Encoder writes data as a request body to w
+type Encoder func(w io.Writer, data any) error
+
+// WithDecoder registers the decoder used by the client to read the responses of the given media type.
+// The media type can use a wildcard subtype ("text/*") or a structured syntax suffix ("application/*+json").
+// Exact media types have precedence over suffixes which have precedence over wildcard subtypes
+func WithDecoder(mediaType string, decoder Decoder) Option {
+	return func(c *Client) {
+		c.decoders = c.decoders.with(mediaType, decoder)
+	}
+}
+
+// WithEncoder registers the encoder used by the client to write the request bodies of the given media type.
+// Media types are matched like for WithDecoder
+func WithEncoder(mediaType string, encoder Encoder) Option {
+	return func(c *Client) {
+		c.encoders = c.encoders.with(mediaType, encoder)
+	}
+}
+
+// mediaTypeRegistry associates values to media types
+type mediaTypeRegistry[T any] map[string]T
+
+// with returns a copy of the registry including the given entry. Registries are shared by copies of a client, so they are never updated in place
+func (r mediaTypeRegistry[T]) with(mediaType string, value T) mediaTypeRegistry[T] {
+	var registry = make(mediaTypeRegistry[T], len(r)+1)
+	for k, v := range r {
+		registry[k] = v
+	}
+	registry[strings.ToLower(mediaType)] = value
+	return registry
+}
+
+// lookup finds the entry matching the media type of the given Content-Type header value
+func (r mediaTypeRegistry[T]) lookup(contentType string) (T, bool) {
+	var mediaType = mediaTypeOf(contentType)
+	if value, ok := r[mediaType]; ok {
+		return value, true
+	}
+
+	var mainType, subType, _ = strings.Cut(mediaType, "/")
+	if idx := strings.LastIndex(subType, "+"); idx >= 0 {
+		if value, ok := r[mainType+"/*"+subType[idx:]]; ok {
+			return value, true
+		}
+	}
+	var value, ok = r[mainType+"/*"]
+	return value, ok
+}
+
+// mediaTypeOf extracts the media type of a Content-Type header value
+func mediaTypeOf(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
+
+func defaultDecoders() mediaTypeRegistry[Decoder] {
+	return mediaTypeRegistry[Decoder]{
+		"application/json":         DecodeJSON,
+		"application/*+json":       DecodeJSON,
+		"text/plain":               DecodeString,
+		"text/html":                DecodeString,
+		"application/octet-stream": DecodeBytes,
+		"application/zip":          DecodeBytes,
+		"application/pdf":          DecodeBytes,
+		"text/xml":                 DecodeXML,
+		"application/xml":          DecodeXML,
+		"application/*+xml":        DecodeXML,
+	}
+}
+
+// DecodeJSON is a Decoder unmarshalling a JSON body into data while it is read. An empty body leaves data unchanged
+func DecodeJSON(body io.Reader, data any) error {
+	if data == nil {
+		_, err := io.Copy(io.Discard, body)
+		return err
+	}
+	var decoder = json.NewDecoder(body)
+	if err := decoder.Decode(data); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return checkJSONEnd(decoder)
+}
+
+// DecodeJSONArray is a Decoder like DecodeJSON which decodes a top-level array into a slice one element at a time, so that the whole
+// array is never held in memory. It uses less memory than DecodeJSON for large arrays but is slower. It can be registered with WithDecoder
+func DecodeJSONArray(body io.Reader, data any) error {
+	var slice, ok = sliceOf(data)
+	if !ok {
+		return DecodeJSON(body, data)
+	}
+	var reader = bufio.NewReader(body)
+	var first, err = peekJSON(reader)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if first != '[' {
+		return DecodeJSON(reader, data)
+	}
+	var decoder = json.NewDecoder(reader)
+	if err = decodeJSONElements(decoder, slice); err != nil {
+		return err
+	}
+	return checkJSONEnd(decoder)
+}
+
+// checkJSONEnd fails when decoder has other content than white spaces after the decoded value, like json.Unmarshal
+func checkJSONEnd(decoder *json.Decoder) error {
+	var _, err = decoder.Token()
+	switch err {
+	case io.EOF:
+		return nil
+	case nil:
+		return fmt.Errorf("%s.%s", MsgErrCannotParse, PrmResponse)
+	default:
+		return err
+	}
+}
+
+// peekJSON skips the leading white spaces of reader and returns the first byte of the JSON value without consuming it
+func peekJSON(reader *bufio.Reader) (byte, error) {
+	for {
+		var c, err = reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c, reader.UnreadByte()
+	}
+}
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// sliceOf returns the slice pointed to by data, unless its type customizes its JSON decoding
+func sliceOf(data any) (reflect.Value, bool) {
+	var value = reflect.ValueOf(data)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, false
+	}
+	if value.Type().Implements(jsonUnmarshalerType) || value.Elem().Type().Implements(jsonUnmarshalerType) {
+		return reflect.Value{}, false
+	}
+	return value.Elem(), true
+}
+
+// decodeJSONElements decodes the JSON array read by decoder into slice, one element at a time
+func decodeJSONElements(decoder *json.Decoder, slice reflect.Value) error {
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	var zero = reflect.Zero(slice.Type().Elem())
+	var result = reflect.MakeSlice(slice.Type(), 0, 0)
+	for decoder.More() {
+		result = reflect.Append(result, zero)
+		if err := decoder.Decode(result.Index(result.Len() - 1).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	slice.Set(result)
+	return nil
+}
+
+// DecodeXML is a Decoder unmarshalling a XML body into data. When data is a *[]byte, the raw body is copied instead
+func DecodeXML(body io.Reader, data any) error {
+	switch data.(type) {
+	case nil:
+		_, err := io.Copy(io.Discard, body)
+		return err
+	case *[]byte:
+		return DecodeBytes(body, data)
+	}
+	var err = xml.NewDecoder(body).Decode(data)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// DecodeString is a Decoder copying the body into data which must be a *string
+func DecodeString(body io.Reader, data any) error {
+	var target, ok = data.(*string)
+	if !ok {
+		return fmt.Errorf("%s.%T", MsgErrUnsupportedDataType, data)
+	}
+	var content, err = io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	*target = string(content)
+	return nil
+}
+
+// DecodeBytes is a Decoder copying the body into data which must be a *[]byte
+func DecodeBytes(body io.Reader, data any) error {
+	var target, ok = data.(*[]byte)
+	if !ok {
+		return fmt.Errorf("%s.%T", MsgErrUnsupportedDataType, data)
+	}
+	var content, err = io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	*target = content
+	return nil
+}
+
+func defaultEncoders() mediaTypeRegistry[Encoder] {
+	return mediaTypeRegistry[Encoder]{
+		MediaTypeJSON:              EncodeJSON,
+		"application/*+json":       EncodeJSON,
+		MediaTypeFormURLEncoded:    EncodeForm,
+		"text/plain":               EncodeRaw,
+		"application/octet-stream": EncodeRaw,
+		"application/zip":          EncodeRaw,
+		"application/pdf":          EncodeRaw,
+		"text/xml":                 EncodeXML,
+		"application/xml":          EncodeXML,
+		"application/*+xml":        EncodeXML,
+	}
+}
+
+// EncodeJSON is an Encoder marshalling data as JSON
+func EncodeJSON(w io.Writer, data any) error {
+	var content, err = json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// EncodeXML is an Encoder marshalling data as XML. A []byte is written as is
+func EncodeXML(w io.Writer, data any) error {
+	if content, ok := data.([]byte); ok {
+		_, err := w.Write(content)
+		return err
+	}
+	return xml.NewEncoder(w).Encode(data)
+}
+
+// EncodeForm is an Encoder writing form fields, given as url.Values, map[string][]string or map[string]string, in the application/x-www-form-urlencoded format
+func EncodeForm(w io.Writer, data any) error {
+	var values url.Values
+	switch fields := data.(type) {
+	case url.Values:
+		values = fields
+	case map[string][]string:
+		values = fields
+	case map[string]string:
+		values = url.Values{}
+		for name, value := range fields {
+			values.Set(name, value)
+		}
+	default:
+		return fmt.Errorf("%s.%T", MsgErrUnsupportedDataType, data)
+	}
+	_, err := io.WriteString(w, values.Encode())
+	return err
+}
+
+// EncodeRaw is an Encoder writing data, which must be a []byte, a string or an io.Reader, as is
+func EncodeRaw(w io.Writer, data any) error {
+	switch content := data.(type) {
+	case []byte:
+		_, err := w.Write(content)
+		return err
+	case string:
+		_, err := io.WriteString(w, content)
+		return err
+	case io.Reader:
+		_, err := io.Copy(w, content)
+		return err
+	default:
+		return fmt.Errorf("%s.%T", MsgErrUnsupportedDataType, data)
+	}
+}
@@ -0,0 +1,242 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaTypeRegistry(t *testing.T) {
+	var registry = mediaTypeRegistry[string]{}.
+		with("application/json", "json").
+		with("application/*+json", "json-suffix").
+		with("text/*", "text").
+		with("application/vnd.cloudtrust.v1+json", "v1")
+
+	for contentType, expected := range map[string]string{
+		"application/json":                   "json",
+		"Application/JSON; charset=utf-8":    "json",
+		"application/problem+json":           "json-suffix",
+		"application/vnd.cloudtrust.v1+json": "v1",
+		"application/vnd.cloudtrust.v2+json": "json-suffix",
+		"text/csv":                           "text",
+	} {
+		var value, ok = registry.lookup(contentType)
+		assert.True(t, ok, contentType)
+		assert.Equal(t, expected, value, contentType)
+	}
+
+	var _, ok = registry.lookup("application/xml")
+	assert.False(t, ok)
+	_, ok = registry.lookup("")
+	assert.False(t, ok)
+}
+
+func TestMediaTypeRegistryIsNotShared(t *testing.T) {
+	var registry = mediaTypeRegistry[string]{"text/plain": "text"}
+	var extended = registry.with("text/csv", "csv")
+	assert.Len(t, registry, 1)
+	assert.Len(t, extended, 2)
+}
+
+func TestDecoders(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		var data map[string]any
+		assert.Nil(t, DecodeJSON(strings.NewReader(`{"key": "value"}`), &data))
+		assert.Equal(t, "value", data["key"])
+		assert.Nil(t, DecodeJSON(strings.NewReader(``), &data))
+		assert.Nil(t, DecodeJSON(strings.NewReader(`{"key": "value"}`), nil))
+		assert.NotNil(t, DecodeJSON(strings.NewReader(`{`), &data))
+		assert.NotNil(t, DecodeJSON(strings.NewReader(`{"a": 1} garbage`), &data))
+		assert.NotNil(t, DecodeJSON(strings.NewReader(`{"a": 1} {"a": 2}`), &data))
+		assert.Nil(t, DecodeJSON(strings.NewReader("{\"a\": 1} \n"), &data))
+	})
+	for name, decode := range map[string]Decoder{"JSON array": DecodeJSON, "JSON array element by element": DecodeJSONArray} {
+		t.Run(name, func(t *testing.T) {
+			var data []sampleEntity
+			assert.Nil(t, decode(strings.NewReader(` [{"key1": "a", "key2": 1}, {"key2": 2}] `), &data))
+			assert.Equal(t, []sampleEntity{{Key1: "a", Key2: 1}, {Key2: 2}}, data)
+
+			assert.Nil(t, decode(strings.NewReader(`[]`), &data))
+			assert.Equal(t, []sampleEntity{}, data)
+			assert.Nil(t, decode(strings.NewReader(`null`), &data))
+			assert.Nil(t, data)
+			assert.Nil(t, decode(strings.NewReader(``), &data))
+
+			var anyData any
+			assert.Nil(t, decode(strings.NewReader(`[1, "two"]`), &anyData))
+			assert.Equal(t, []any{float64(1), "two"}, anyData)
+
+			assert.NotNil(t, decode(strings.NewReader(`[{"key1": "a"}, {"key1": 2}]`), &data))
+			assert.NotNil(t, decode(strings.NewReader(`[{"key1": "a"}`), &data))
+			assert.NotNil(t, decode(strings.NewReader(`{"key1": "a"}`), &data))
+			assert.NotNil(t, decode(strings.NewReader(`[{"key1": "a"}] garbage`), &data))
+		})
+	}
+	t.Run("JSON array element by element replaces stale elements", func(t *testing.T) {
+		var data = []sampleEntity{{Key1: "stale"}, {Key1: "stale"}, {Key1: "stale"}}
+		assert.Nil(t, DecodeJSONArray(strings.NewReader(`[{"key1": "a", "key2": 1}, {"key2": 2}]`), &data))
+		assert.Equal(t, []sampleEntity{{Key1: "a", Key2: 1}, {Key2: 2}}, data)
+	})
+	t.Run("XML", func(t *testing.T) {
+		type assertion struct {
+			ID     string `xml:"ID,attr"`
+			Issuer string `xml:"Issuer"`
+		}
+		var body = `<Assertion ID="abc"><Issuer>https://idp.example.com</Issuer></Assertion>`
+		var data assertion
+		assert.Nil(t, DecodeXML(strings.NewReader(body), &data))
+		assert.Equal(t, assertion{ID: "abc", Issuer: "https://idp.example.com"}, data)
+
+		var raw []byte
+		assert.Nil(t, DecodeXML(strings.NewReader(body), &raw))
+		assert.Equal(t, []byte(body), raw)
+
+		assert.Nil(t, DecodeXML(strings.NewReader(body), nil))
+		assert.Nil(t, DecodeXML(strings.NewReader(""), &data))
+		assert.NotNil(t, DecodeXML(strings.NewReader("<Assertion>"), &data))
+	})
+	t.Run("String", func(t *testing.T) {
+		var data string
+		assert.Nil(t, DecodeString(strings.NewReader("content"), &data))
+		assert.Equal(t, "content", data)
+		var wrongType []byte
+		assert.NotNil(t, DecodeString(strings.NewReader("content"), &wrongType))
+	})
+	t.Run("Bytes", func(t *testing.T) {
+		var data []byte
+		assert.Nil(t, DecodeBytes(strings.NewReader("content"), &data))
+		assert.Equal(t, []byte("content"), data)
+		var wrongType string
+		assert.NotNil(t, DecodeBytes(strings.NewReader("content"), &wrongType))
+	})
+}
+
+func TestWithDecoder(t *testing.T) {
+	var decodeCSV = func(body io.Reader, data any) error {
+		var target, ok = data.(*[]string)
+		if !ok {
+			return errors.New("unexpected type")
+		}
+		var content, err = io.ReadAll(body)
+		*target = strings.Split(string(content), ",")
+		return err
+	}
+	var client, _ = NewWithOptions("http://my.url", time.Minute,
+		WithDecoder("text/csv", decodeCSV),
+		WithDecoder("application/vnd.cloudtrust.v2+json", func(body io.Reader, data any) error {
+			var envelope struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if err := json.NewDecoder(body).Decode(&envelope); err != nil {
+				return err
+			}
+			return json.Unmarshal(envelope.Data, data)
+		}))
+
+	t.Run("Custom media type", func(t *testing.T) {
+		var data []string
+		var err = client.readContent(createResponse("text/csv", "a,b,c"), &data)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, data)
+	})
+	t.Run("Vendor JSON media type", func(t *testing.T) {
+		var data map[string]string
+		var err = client.readContent(createResponse("application/vnd.cloudtrust.v2+json", `{"data": {"key": "value"}}`), &data)
+		assert.Nil(t, err)
+		assert.Equal(t, "value", data["key"])
+	})
+	t.Run("XML media types", func(t *testing.T) {
+		type entity struct {
+			Name string `xml:"name"`
+		}
+		for _, contentType := range []string{"text/xml", "application/xml; charset=utf-8", "application/samlmetadata+xml"} {
+			var data entity
+			var err = client.readContent(createResponse(contentType, `<entity><name>value</name></entity>`), &data)
+			assert.Nil(t, err, contentType)
+			assert.Equal(t, "value", data.Name, contentType)
+		}
+	})
+	t.Run("Other JSON media types use the default decoder", func(t *testing.T) {
+		var data map[string]string
+		var err = client.readContent(createResponse("application/vnd.cloudtrust.v1+json", `{"key": "value"}`), &data)
+		assert.Nil(t, err)
+		assert.Equal(t, "value", data["key"])
+	})
+}
+
+func TestEncoders(t *testing.T) {
+	var encode = func(encoder Encoder, data any) (string, error) {
+		var buffer strings.Builder
+		var err = encoder(&buffer, data)
+		return buffer.String(), err
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		var content, err = encode(EncodeJSON, sampleEntity{Key1: "value", Key2: 2})
+		assert.Nil(t, err)
+		assert.Equal(t, `{"key1":"value","key2":2}`, content)
+	})
+	t.Run("XML", func(t *testing.T) {
+		type entity struct {
+			XMLName struct{} `xml:"entity"`
+			Name    string   `xml:"name"`
+		}
+		var content, err = encode(EncodeXML, entity{Name: "value"})
+		assert.Nil(t, err)
+		assert.Equal(t, `<entity><name>value</name></entity>`, content)
+
+		content, err = encode(EncodeXML, []byte(`<raw/>`))
+		assert.Nil(t, err)
+		assert.Equal(t, `<raw/>`, content)
+	})
+	t.Run("Form", func(t *testing.T) {
+		var content, err = encode(EncodeForm, map[string]string{"grant_type": "password", "username": "a b"})
+		assert.Nil(t, err)
+		assert.Equal(t, "grant_type=password&username=a+b", content)
+
+		content, err = encode(EncodeForm, map[string][]string{"scope": {"openid", "email"}})
+		assert.Nil(t, err)
+		assert.Equal(t, "scope=openid&scope=email", content)
+
+		_, err = encode(EncodeForm, sampleEntity{})
+		assert.Contains(t, err.Error(), MsgErrUnsupportedDataType)
+	})
+	t.Run("Raw", func(t *testing.T) {
+		for _, data := range []any{[]byte("content"), "content", strings.NewReader("content")} {
+			var content, err = encode(EncodeRaw, data)
+			assert.Nil(t, err)
+			assert.Equal(t, "content", content)
+		}
+		var _, err = encode(EncodeRaw, 12)
+		assert.Contains(t, err.Error(), MsgErrUnsupportedDataType)
+	})
+}
+
+func TestWithEncoder(t *testing.T) {
+	var client, _ = NewWithOptions("http://my.url", time.Minute,
+		WithEncoder("text/csv", func(w io.Writer, data any) error {
+			var values, ok = data.([]string)
+			if !ok {
+				return errors.New("unexpected type")
+			}
+			var _, err = io.WriteString(w, strings.Join(values, ","))
+			return err
+		}))
+
+	var encoder, ok = client.encoders.lookup("text/csv; charset=utf-8")
+	assert.True(t, ok)
+	var buffer strings.Builder
+	assert.Nil(t, encoder(&buffer, []string{"a", "b", "c"}))
+	assert.Equal(t, "a,b,c", buffer.String())
+
+	_, ok = client.encoders.lookup("application/vnd.cloudtrust.v1+json")
+	assert.True(t, ok)
+	_, ok = client.encoders.lookup("text/html")
+	assert.False(t, ok)
+}
@@ -0,0 +1,227 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/headers"
+)
+
+// Download is a HTTP GET method writing the response body to w while it is received. It returns the number of bytes written
+func (c *Client) Download(w io.Writer, plugins ...plugin.Plugin) (int64, error) {
+	return c.DownloadCtx(context.Background(), w, plugins...)
+}
+
+// DownloadCtx is a HTTP GET method bound to the context ctx writing the response body to w while it is received. It returns the number of bytes written.
+// When the transfer of the body fails, the download is resumed according to the retry policy of the client, using a Range request validated with
+// the ETag or the Last-Modified date of the first response. When the server ignores the range, the download restarts from the beginning provided
+// that w can be emptied, which is the case for an *os.File or a *bytes.Buffer. When writing to w fails, the response body is closed without being read further
+func (c *Client) DownloadCtx(ctx context.Context, w io.Writer, plugins ...plugin.Plugin) (int64, error) {
+	var written int64
+	var validator string
+	var maxAttempts = c.retryPolicy.maxAttempts(http.MethodGet)
+	for attempt := 1; ; attempt++ {
+		var requestPlugins = append([]plugin.Plugin{headers.Set("Accept-Encoding", "identity")}, plugins...)
+		if written > 0 {
+			requestPlugins = append(requestPlugins, headers.Set("Range", fmt.Sprintf("bytes=%d-", written)), headers.Set("If-Range", validator))
+		}
+
+		var err = c.do(ctx, http.MethodGet, func(resp *Response) error {
+			if written > 0 && resp.StatusCode == http.StatusPartialContent {
+				if start, _, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || start != written {
+					return fmt.Errorf("%s.%s", MsgErrInvalidContentRange, PrmResponse)
+				}
+			} else {
+				if written > 0 {
+					if err := resetWriter(w); err != nil {
+						return err
+					}
+					written = 0
+				}
+				validator = rangeValidator(resp.Header)
+			}
+			var n, err = io.Copy(w, bodyReader{resp.Reader()})
+			written += n
+			return err
+		}, requestPlugins...)
+
+		var readErr bodyReadError
+		if err == nil || !errors.As(err, &readErr) || !c.retryPolicy.retryError(readErr.err) || attempt >= maxAttempts {
+			return written, err
+		}
+		if validator == "" && written > 0 {
+			if resetErr := resetWriter(w); resetErr != nil {
+				return written, err
+			}
+			written = 0
+		}
+		if sleepErr := sleepContext(ctx, c.retryPolicy.backoff(attempt)); sleepErr != nil {
+			return written, errors.Wrap(&canceledError{err: sleepErr}, MsgErrCanceled+"."+PrmRequest)
+		}
+	}
+}
+
+// DownloadFile is a HTTP GET method writing the response body to the file at path. It returns the number of bytes written.
+// The body is written to a temporary file which replaces the file at path once the download succeeded
+func (c *Client) DownloadFile(path string, plugins ...plugin.Plugin) (int64, error) {
+	return c.DownloadFileCtx(context.Background(), path, plugins...)
+}
+
+// DownloadFileCtx is a HTTP GET method bound to the context ctx writing the response body to the file at path. It returns the number of bytes written.
+// The body is written to a temporary file which replaces the file at path once the download succeeded
+func (c *Client) DownloadFileCtx(ctx context.Context, path string, plugins ...plugin.Plugin) (int64, error) {
+	return downloadFile(path, func(w io.Writer) (int64, error) {
+		return c.DownloadCtx(ctx, w, plugins...)
+	})
+}
+
+// downloadFile lets download write into a temporary file created next to path, then renames it to path.
+// The file gets the permissions of the file it replaces or, when there is none, the ones of a file created by os.Create.
+// The temporary file is removed when the download fails
+func downloadFile(path string, download func(w io.Writer) (int64, error)) (int64, error) {
+	var perm os.FileMode = 0666
+	var info, statErr = os.Stat(path)
+	if statErr == nil {
+		perm = info.Mode().Perm()
+	}
+	var file, err = createTempFile(filepath.Dir(path), "."+filepath.Base(path), perm)
+	if err == nil && statErr == nil {
+		// The permissions of the replaced file are kept even when the umask would restrict them
+		if err = file.Chmod(perm); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, MsgErrCannotWrite+"."+PrmFile)
+	}
+	var tempPath = file.Name()
+	var committed = false
+	defer func() {
+		if !committed {
+			file.Close()
+			os.Remove(tempPath)
+		}
+	}()
+
+	var written int64
+	written, err = download(file)
+	if err != nil {
+		return written, err
+	}
+	if err = file.Sync(); err != nil {
+		return written, errors.Wrap(err, MsgErrCannotWrite+"."+PrmFile)
+	}
+	if err = file.Close(); err != nil {
+		return written, errors.Wrap(err, MsgErrCannotWrite+"."+PrmFile)
+	}
+	if err = os.Rename(tempPath, path); err != nil {
+		return written, errors.Wrap(err, MsgErrCannotWrite+"."+PrmFile)
+	}
+	committed = true
+	return written, nil
+}
+
+// createTempFile creates a new file in dir whose name starts with prefix. Unlike os.CreateTemp, the file is created with the
+// permissions perm, restricted by the umask
+func createTempFile(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	for try := 0; ; try++ {
+		var name = filepath.Join(dir, prefix+"."+strconv.FormatUint(uint64(rand.Uint32()), 10)+".tmp")
+		var file, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) && try < 100 {
+			continue
+		}
+		return file, err
+	}
+}
+
+// bodyReader tags the errors occurring while reading a response body
+type bodyReader struct {
+	body io.Reader
+}
+
+func (r bodyReader) Read(p []byte) (int, error) {
+	var n, err = r.body.Read(p)
+	if err != nil && err != io.EOF {
+		err = bodyReadError{err: err}
+	}
+	return n, err
+}
+
+// bodyReadError is returned when the transfer of a response body failed
+type bodyReadError struct {
+	err error
+}
+
+func (e bodyReadError) Error() string {
+	return e.err.Error()
+}
+
+func (e bodyReadError) Unwrap() error {
+	return e.err
+}
+
+// rangeValidator returns the validator used in the If-Range header of the requests resuming the download of a response having the given headers.
+// Weak entity tags can not be used in If-Range: the Last-Modified date is used instead
+func rangeValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// parseContentRange returns the position of the first byte and the complete length given by a Content-Range header value ("bytes 100-199/200").
+// The complete length is -1 when unknown ("bytes 100-199/*")
+func parseContentRange(contentRange string) (int64, int64, bool) {
+	var byteRange, found = strings.CutPrefix(contentRange, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	var positions, length, _ = strings.Cut(byteRange, "/")
+	var start, _, ok = strings.Cut(positions, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	var position, err = strconv.ParseInt(start, 10, 64)
+	if err != nil || position < 0 {
+		return 0, 0, false
+	}
+	var total = int64(-1)
+	if length != "*" {
+		if total, err = strconv.ParseInt(length, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return position, total, true
+}
+
+// resetWriter empties w so that a download can restart from the beginning
+func resetWriter(w io.Writer) error {
+	switch writer := w.(type) {
+	case interface{ Reset() }:
+		writer.Reset()
+		return nil
+	case interface {
+		io.Seeker
+		Truncate(size int64) error
+	}:
+		if err := writer.Truncate(0); err != nil {
+			return errors.Wrap(err, MsgErrCannotWrite+"."+PrmFile)
+		}
+		if _, err := writer.Seek(0, io.SeekStart); err != nil {
+			return errors.Wrap(err, MsgErrCannotWrite+"."+PrmFile)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s.%s", MsgErrCannotRestart, PrmResponse)
+	}
+}
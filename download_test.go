@@ -0,0 +1,303 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+func TestDownload(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/export"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = New(ts.URL, time.Minute)
+	var content = strings.Repeat("0123456789", 100000)
+
+	var respondWith = func(status int, body string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method)
+			w.Header().Set("Content-Type", "application/zip")
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}
+	}
+
+	t.Run("Writer", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, content))
+		var buffer bytes.Buffer
+		var written, err = client.Download(&buffer, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(content)), written)
+		assert.Equal(t, content, buffer.String())
+	})
+	t.Run("Error status", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusNotFound, "not found"))
+		var buffer bytes.Buffer
+		var written, err = client.DownloadCtx(context.Background(), &buffer, url.Path(path))
+		assert.True(t, errors.Is(err, ErrNotFound))
+		assert.Equal(t, int64(0), written)
+		assert.Equal(t, 0, buffer.Len())
+	})
+	t.Run("File", func(t *testing.T) {
+		var dir = t.TempDir()
+		var file = filepath.Join(dir, "export.zip")
+		os.WriteFile(file, []byte("previous export"), 0600)
+
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, content))
+		var written, err = client.DownloadFile(file, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(content)), written)
+
+		var saved, _ = os.ReadFile(file)
+		assert.Equal(t, content, string(saved))
+		var entries, _ = os.ReadDir(dir)
+		assert.Len(t, entries, 1)
+	})
+	t.Run("File permissions", func(t *testing.T) {
+		var dir = t.TempDir()
+		var reference, _ = os.Create(filepath.Join(dir, "reference"))
+		reference.Close()
+		var expected, _ = os.Stat(reference.Name())
+
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, content)).Times(2)
+		var file = filepath.Join(dir, "export.zip")
+		var _, err = client.DownloadFile(file, url.Path(path))
+		assert.Nil(t, err)
+		var info, _ = os.Stat(file)
+		assert.Equal(t, expected.Mode().Perm(), info.Mode().Perm())
+
+		os.Chmod(file, 0604)
+		_, err = client.DownloadFile(file, url.Path(path))
+		assert.Nil(t, err)
+		info, _ = os.Stat(file)
+		assert.Equal(t, os.FileMode(0604), info.Mode().Perm())
+	})
+	t.Run("File is left unchanged when the download fails", func(t *testing.T) {
+		var dir = t.TempDir()
+		var file = filepath.Join(dir, "export.zip")
+		os.WriteFile(file, []byte("previous export"), 0600)
+
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, content))
+		var _, err = client.DownloadFileCtx(context.Background(), file, url.Path(path), MaxBodySize(1000))
+		assert.True(t, errors.Is(err, ErrBodyTooLarge))
+
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusInternalServerError, "failure"))
+		_, err = client.DownloadFileCtx(context.Background(), file, url.Path(path))
+		assert.True(t, errors.Is(err, ErrServerError))
+
+		var saved, _ = os.ReadFile(file)
+		assert.Equal(t, "previous export", string(saved))
+		var entries, _ = os.ReadDir(dir)
+		assert.Len(t, entries, 1)
+	})
+	t.Run("Directory does not exist", func(t *testing.T) {
+		var _, err = client.DownloadFile(filepath.Join(t.TempDir(), "missing", "export.zip"), url.Path(path))
+		assert.Contains(t, err.Error(), MsgErrCannotWrite+"."+PrmFile)
+	})
+}
+
+// failingWriter fails once more than limit bytes are written
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		return 0, errors.New("disk full")
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestDownloadWriterFails(t *testing.T) {
+	var release = make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("0"), 256<<10))
+		w.(http.Flusher).Flush()
+		// The rest of the body is only sent once the test ends
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(testRetryPolicy()))
+	var start = time.Now()
+	var _, err = client.Download(&failingWriter{limit: 64 << 10})
+	assert.Equal(t, "disk full", err.Error())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestResumeDownload(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/export"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(testRetryPolicy()))
+	var content = strings.Repeat("0123456789", 10000)
+	var modTime = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	// interrupted sends the headers of the whole content but only the first half of the body
+	var interrupted = func(etag string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "identity", r.Header.Get("Accept-Encoding"))
+			assert.Equal(t, "", r.Header.Get("Range"))
+			if etag != "" {
+				w.Header().Set("ETag", etag)
+			}
+			w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(content[:len(content)/2]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+	}
+	var serveContent = func(etag string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if etag != "" {
+				w.Header().Set("ETag", etag)
+			}
+			http.ServeContent(w, r, "", modTime, strings.NewReader(content))
+		}
+	}
+
+	t.Run("Resumed with the ETag", func(t *testing.T) {
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(interrupted(`"v1"`)),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, fmt.Sprintf("bytes=%d-", len(content)/2), r.Header.Get("Range"))
+				assert.Equal(t, `"v1"`, r.Header.Get("If-Range"))
+				serveContent(`"v1"`)(w, r)
+			}),
+		)
+		var buffer bytes.Buffer
+		var written, err = client.Download(&buffer, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(content)), written)
+		assert.Equal(t, content, buffer.String())
+	})
+	t.Run("Resumed with the Last-Modified date", func(t *testing.T) {
+		var file = filepath.Join(t.TempDir(), "export.zip")
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(interrupted(`W/"weak"`)),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, modTime.Format(http.TimeFormat), r.Header.Get("If-Range"))
+				serveContent("")(w, r)
+			}),
+		)
+		var written, err = client.DownloadFile(file, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(content)), written)
+		var saved, _ = os.ReadFile(file)
+		assert.Equal(t, content, string(saved))
+	})
+	t.Run("Restarted when the content changed", func(t *testing.T) {
+		var file = filepath.Join(t.TempDir(), "export.zip")
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(interrupted(`"v1"`)),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(serveContent(`"v2"`)),
+		)
+		var written, err = client.DownloadFile(file, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(content)), written)
+		var saved, _ = os.ReadFile(file)
+		assert.Equal(t, content, string(saved))
+	})
+	t.Run("Server ignores ranges", func(t *testing.T) {
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(interrupted(`"v1"`)),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(content))
+			}),
+		)
+		var buffer bytes.Buffer
+		var written, err = client.Download(&buffer, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(content)), written)
+		assert.Equal(t, content, buffer.String())
+	})
+	t.Run("Writer can't be emptied", func(t *testing.T) {
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(interrupted("")),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(content))
+			}),
+		)
+		var hash = sha256.New()
+		var _, err = client.Download(struct{ io.Writer }{hash}, url.Path(path))
+		assert.Contains(t, err.Error(), MsgErrCannotRestart)
+	})
+	t.Run("Invalid Content-Range", func(t *testing.T) {
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(interrupted(`"v1"`)),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(content))
+			}),
+		)
+		var _, err = client.Download(io.Discard, url.Path(path))
+		assert.Contains(t, err.Error(), MsgErrInvalidContentRange)
+	})
+	t.Run("Not resumed without retry policy", func(t *testing.T) {
+		var client, _ = New(ts.URL, time.Minute)
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(interrupted(`"v1"`))
+		var written, err = client.Download(io.Discard, url.Path(path))
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		assert.Equal(t, int64(len(content)/2), written)
+	})
+}
+
+func TestParseContentRange(t *testing.T) {
+	var start, total, ok = parseContentRange("bytes 100-199/200")
+	assert.True(t, ok)
+	assert.Equal(t, int64(100), start)
+	assert.Equal(t, int64(200), total)
+
+	start, total, ok = parseContentRange("bytes 0-99/*")
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), start)
+	assert.Equal(t, int64(-1), total)
+
+	for _, invalid := range []string{"", "bytes */200", "items 0-1/2", "bytes -5-10/20", "bytes 0-1/many"} {
+		_, _, ok = parseContentRange(invalid)
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultErrorBodyLimit is the maximum number of bytes of an error response body kept in the message of an HTTPError
+const DefaultErrorBodyLimit = 4 << 10
+
+// errorBodyReadFactor is the number of times the error body limit which is read from an error response body
+const errorBodyReadFactor = 4
+
+// truncatedSuffix is appended to the error messages which were truncated
+const truncatedSuffix = "...(truncated)"
+
+// ErrorBodyRedactor removes the secrets of an error response body before it is placed in an HTTPError
+type ErrorBodyRedactor func(body []byte) []byte
+
+// WithErrorBodyLimit bounds the number of bytes of an error response body kept in the message of an HTTPError.
+// A limit lower or equal to zero keeps the whole body
+func WithErrorBodyLimit(limit int) Option {
+	return func(c *Client) {
+		c.errorBodyLimit = limit
+	}
+}
+
+// WithErrorBodyRedactors adds redactors which are applied, in order, to the error response bodies before they are parsed and placed in an HTTPError
+func WithErrorBodyRedactors(redactors ...ErrorBodyRedactor) Option {
+	return func(c *Client) {
+		c.errorBodyRedactors = append(c.errorBodyRedactors, redactors...)
+	}
+}
+
+// RedactPattern returns a redactor replacing the parts of the body matching pattern with "REDACTED".
+// When the pattern has capturing groups, only the text matched by the groups is replaced
+func RedactPattern(pattern *regexp.Regexp) ErrorBodyRedactor {
+	return func(body []byte) []byte {
+		if pattern.NumSubexp() == 0 {
+			return pattern.ReplaceAll(body, []byte(redacted))
+		}
+		var result []byte
+		var last = 0
+		for _, indexes := range pattern.FindAllSubmatchIndex(body, -1) {
+			for i := 2; i+1 < len(indexes); i += 2 {
+				if indexes[i] < last {
+					continue
+				}
+				result = append(result, body[last:indexes[i]]...)
+				result = append(result, redacted...)
+				last = indexes[i+1]
+			}
+		}
+		return append(result, body[last:]...)
+	}
+}
+
+// RedactJSONKeys returns a redactor replacing with "REDACTED" the values of the members of a JSON body whose name is one of keys, ignoring case.
+// Nested objects and arrays are redacted as well. Bodies which are not valid JSON are left unchanged
+func RedactJSONKeys(keys ...string) ErrorBodyRedactor {
+	var names = map[string]bool{}
+	for _, key := range keys {
+		names[strings.ToLower(key)] = true
+	}
+	return func(body []byte) []byte {
+		var decoder = json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil || decoder.More() {
+			return body
+		}
+		if !redactJSONValue(value, names) {
+			return body
+		}
+		var result, err = json.Marshal(value)
+		if err != nil {
+			return body
+		}
+		return result
+	}
+}
+
+// redactJSONValue redacts value in place and tells whether something was redacted
+func redactJSONValue(value any, names map[string]bool) bool {
+	var changed = false
+	switch v := value.(type) {
+	case map[string]any:
+		for name, member := range v {
+			if names[strings.ToLower(name)] {
+				v[name] = redacted
+				changed = true
+			} else if redactJSONValue(member, names) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if redactJSONValue(item, names) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// errorBody returns the body of the error response resp once redacted. Only a multiple of the error body limit, or of the default limit
+// when it is lower, is read. The extra content lets structured error bodies be parsed before their message is truncated
+func (c *Client) errorBody(resp *internalResponse) []byte {
+	var body []byte
+	if c.errorBodyLimit > 0 {
+		body = resp.readAtMost(int64(max(c.errorBodyLimit, DefaultErrorBodyLimit)) * errorBodyReadFactor)
+	} else {
+		// The message is built from the content read before a failure, such as a body exceeding the maximum size
+		body, _ = resp.read()
+	}
+	for _, redactor := range c.errorBodyRedactors {
+		body = redactor(body)
+	}
+	return body
+}
+
+// truncateErrorMessage bounds the message of the HTTPError carried by err to the error body limit of the client
+func (c *Client) truncateErrorMessage(err error) error {
+	return updateHTTPError(err, func(e *HTTPError) {
+		e.Message = truncate(e.Message, c.errorBodyLimit)
+	})
+}
+
+// truncate cuts text to at most limit bytes without splitting a UTF-8 character
+func truncate(text string, limit int) string {
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+	var end = limit
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end] + truncatedSuffix
+}
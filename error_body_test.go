@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorBodyLimit(t *testing.T) {
+	var page = "<html>" + strings.Repeat("x", 2*DefaultErrorBodyLimit) + "</html>"
+
+	t.Run("Default limit", func(t *testing.T) {
+		var client, _ = New("http://my.url", time.Minute)
+		var err = client.checkError(createErrorResponse(http.StatusBadGateway, "text/html", page))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusBadGateway, Message: page[:DefaultErrorBodyLimit] + truncatedSuffix}, err)
+	})
+	t.Run("Custom limit", func(t *testing.T) {
+		var client, _ = NewWithOptions("http://my.url", time.Minute, WithErrorBodyLimit(11))
+		var err = client.checkError(createErrorResponse(http.StatusConflict, "application/json", `{"errorMessage": "User exists with same username"}`))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusConflict, Message: "User exists" + truncatedSuffix}, err)
+	})
+	t.Run("No limit", func(t *testing.T) {
+		var client, _ = NewWithOptions("http://my.url", time.Minute, WithErrorBodyLimit(0))
+		var err = client.checkError(createErrorResponse(http.StatusBadGateway, "text/html", page))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusBadGateway, Message: page}, err)
+	})
+}
+
+func TestErrorBodyIsReadPartially(t *testing.T) {
+	var chunk = []byte(strings.Repeat("x", 1<<10))
+	var size = 64 << 20
+	var written = make(chan int, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		var total int
+		for total < size {
+			var n, err = w.Write(chunk)
+			total += n
+			if err != nil {
+				break
+			}
+		}
+		written <- total
+	}))
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithErrorBodyLimit(100))
+	var err = client.Get(nil)
+	var httpErr HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, strings.Repeat("x", 100)+truncatedSuffix, httpErr.Message)
+	assert.Less(t, <-written, size)
+}
+
+func TestErrorBodyRedactors(t *testing.T) {
+	var client, _ = NewWithOptions("http://my.url", time.Minute, WithErrorBodyRedactors(
+		RedactJSONKeys("password", "client_secret"),
+		RedactPattern(regexp.MustCompile(`token=(\w+)`)),
+	))
+
+	t.Run("JSON keys", func(t *testing.T) {
+		var err = client.checkError(createErrorResponse(http.StatusBadRequest, "application/json", `{"user": {"name": "john", "Password": "secret"}, "client_secret": "s3cr3t"}`))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusBadRequest, Message: `{"client_secret":"REDACTED","user":{"Password":"REDACTED","name":"john"}}`}, err)
+	})
+	t.Run("Redacted before parsing", func(t *testing.T) {
+		var err = client.checkError(createErrorResponse(http.StatusUnprocessableEntity, MediaTypeProblemJSON, `{"title": "Invalid", "password": "secret"}`))
+		var problem = err.(ProblemError)
+		assert.Equal(t, map[string]any{"password": "REDACTED"}, problem.Extensions)
+	})
+	t.Run("Pattern", func(t *testing.T) {
+		var err = client.checkError(createErrorResponse(http.StatusInternalServerError, "text/plain", "cannot validate token=abc123 for user"))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusInternalServerError, Message: "cannot validate token=REDACTED for user"}, err)
+	})
+}
+
+func TestRedactPattern(t *testing.T) {
+	var redactor = RedactPattern(regexp.MustCompile(`\d{4}-\d{4}`))
+	assert.Equal(t, "card REDACTED and REDACTED", string(redactor([]byte("card 1234-5678 and 8765-4321"))))
+
+	redactor = RedactPattern(regexp.MustCompile(`"(?:password|secret)":"([^"]*)"`))
+	assert.Equal(t, `{"password":"REDACTED","secret":"REDACTED","name":"john"}`,
+		string(redactor([]byte(`{"password":"p","secret":"s","name":"john"}`))))
+}
+
+func TestRedactJSONKeys(t *testing.T) {
+	var redactor = RedactJSONKeys("token")
+	assert.Equal(t, `[{"id":12345678901234567890,"token":"REDACTED"}]`, string(redactor([]byte(`[{"id": 12345678901234567890, "token": "abc"}]`))))
+
+	for _, unchanged := range []string{`not json`, `{"id": 1} {"id": 2}`, `{"name": "john"}`} {
+		assert.Equal(t, unchanged, string(redactor([]byte(unchanged))))
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "long", truncate("long", 0))
+	assert.Equal(t, "caf"+truncatedSuffix, truncate("café au lait", 4))
+}
@@ -1,32 +1,123 @@
 package httpclient
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // Constants for error management
 const (
+	MsgErrCanceled                  = "canceled"
 	MsgErrCannotObtain              = "cannotObtain"
 	MsgErrCannotGetIssuer           = "cannotGetIssuer"
 	MsgErrCannotParse               = "cannotParse"
+	MsgErrCannotRestart             = "cannotRestart"
+	MsgErrCannotWrite               = "cannotWrite"
+	MsgErrCircuitOpen               = "circuitOpen"
+	MsgErrInvalidContentRange       = "invalidContentRange"
 	MsgErrUnkownHTTPContentType     = "unkownHTTPContentType"
 	MsgErrUnknownResponseStatusCode = "unknownResponseStatusCode"
+	MsgErrUnsupportedDataType       = "unsupportedDataType"
 
 	PrmTokenProviderURL = "tokenProviderURL"
 	PrmAPIURL           = "APIURL"
 	PrmTokenMsg         = "token"
 	PrmResponse         = "response"
+	PrmRequest          = "request"
+	PrmFile             = "file"
+
+	HeaderRequestID = "X-Request-ID"
+)
+
+// Sentinel errors which can be tested with errors.Is
+var (
+	// ErrCircuitOpen is returned instead of sending a request while the circuit breaker of the client is open
+	ErrCircuitOpen = errors.New(MsgErrCircuitOpen)
+	// ErrTimeout matches the requests which timed out before a response was received, including those whose context deadline expired
+	ErrTimeout = errors.New("timeout")
+	// ErrConnection matches the other failures to obtain a response (refused or reset connections, DNS failures, ...)
+	ErrConnection = errors.New("connectionFailure")
+	// ErrBodyTooLarge is returned when a response body exceeds the maximum size allowed by WithMaxBodySize or MaxBodySize
+	ErrBodyTooLarge = errors.New("bodyTooLarge")
+
+	// ErrClientError matches the HTTP errors with a 4xx status code
+	ErrClientError = errors.New("clientError")
+	// ErrServerError matches the HTTP errors with a 5xx status code
+	ErrServerError = errors.New("serverError")
+	// ErrBadRequest matches the HTTP errors with a 400 status code
+	ErrBadRequest = errors.New("badRequest")
+	// ErrUnauthorized matches the HTTP errors with a 401 status code
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden matches the HTTP errors with a 403 status code
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound matches the HTTP errors with a 404 status code
+	ErrNotFound = errors.New("notFound")
+	// ErrConflict matches the HTTP errors with a 409 status code
+	ErrConflict = errors.New("conflict")
+	// ErrTooManyRequests matches the HTTP errors with a 429 status code
+	ErrTooManyRequests = errors.New("tooManyRequests")
 )
 
 // HTTPError is returned when an error occured while contacting the keycloak instance.
 type HTTPError struct {
 	StatusCode int
 	Message    string
+	// Method is the HTTP method of the failed request
+	Method string
+	// URL is the URL of the failed request, its secrets being redacted
+	URL string
+	// header contains the headers of the error response. It is held by a pointer to keep HTTPError comparable
+	header *http.Header
+	// RequestID is the X-Request-ID of the response, or of the request when the server did not send any
+	RequestID string
+	// Duration is the time elapsed between the first attempt and the reception of the error response
+	Duration time.Duration
+	// Attempts is the number of requests sent before giving up
+	Attempts int
+	// RetryAfter is the delay requested by the server through the Retry-After header of a 429 or 503 response
+	RetryAfter time.Duration
 }
 
 func (e HTTPError) Error() string {
-	return fmt.Sprintf("%d:%s", e.StatusCode, e.Message)
+	var msg = fmt.Sprintf("%d:%s", e.StatusCode, e.Message)
+	if e.Method != "" {
+		msg = fmt.Sprintf("%s %s: %s", e.Method, e.URL, msg)
+	}
+	if e.Attempts > 1 {
+		msg = fmt.Sprintf("%s (after %d attempts)", msg, e.Attempts)
+	}
+	return msg
+}
+
+// Format implements fmt.Formatter. The %+v verb adds the request ID and the duration to the error message
+func (e HTTPError) Format(s fmt.State, verb rune) {
+	var msg = e.Error()
+	if verb == 'v' && s.Flag('+') {
+		if e.RequestID != "" {
+			msg += " requestID=" + e.RequestID
+		}
+		if e.Duration > 0 {
+			msg += " duration=" + e.Duration.String()
+		}
+	}
+	if verb == 'q' {
+		msg = strconv.Quote(msg)
+	}
+	io.WriteString(s, msg)
+}
+
+// Header returns the headers of the error response
+func (e HTTPError) Header() http.Header {
+	if e.header == nil {
+		return nil
+	}
+	return *e.header
 }
 
 // IsSuccess is true when HTTP status code is 2xx or 3xx
@@ -58,3 +149,92 @@ func (e HTTPError) Status() int {
 func (e HTTPError) ErrorMessage() string {
 	return e.Message
 }
+
+// Is makes errors.Is match an HTTPError with the sentinel errors of its status code and status class
+func (e HTTPError) Is(target error) bool {
+	switch target {
+	case ErrClientError:
+		return e.IsErrorFromClient()
+	case ErrServerError:
+		return e.IsErrorFromServer()
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
+
+// updateHTTPError applies update to the HTTPError carried by err
+func updateHTTPError(err error, update func(*HTTPError)) error {
+	switch e := err.(type) {
+	case HTTPError:
+		update(&e)
+		return e
+	case ProblemError:
+		update(&e.HTTPError)
+		return e
+	case KeycloakError:
+		update(&e.HTTPError)
+		return e
+	default:
+		return err
+	}
+}
+
+// canceledError is returned when the context of a request is done before a response is received
+type canceledError struct {
+	err error
+}
+
+func (e *canceledError) Error() string {
+	return e.err.Error()
+}
+
+func (e *canceledError) Unwrap() error {
+	return e.err
+}
+
+// Is makes errors.Is match a canceledError with ErrTimeout when the deadline of the context expired
+func (e *canceledError) Is(target error) bool {
+	return target == ErrTimeout && errors.Is(e.err, context.DeadlineExceeded)
+}
+
+// transportError is returned when a request could not be transmitted or when no response was received
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// Is makes errors.Is match a transportError with ErrTimeout or ErrConnection
+func (e *transportError) Is(target error) bool {
+	switch target {
+	case ErrTimeout:
+		return e.timeout()
+	case ErrConnection:
+		return !e.timeout()
+	default:
+		return false
+	}
+}
+
+func (e *transportError) timeout() bool {
+	var netErr net.Error
+	return errors.Is(e.err, context.DeadlineExceeded) || (errors.As(e.err, &netErr) && netErr.Timeout())
+}
@@ -1,10 +1,25 @@
 package httpclient
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	pkgerrors "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/query"
+	urlplugin "gopkg.in/h2non/gentleman.v2/plugins/url"
 )
 
 func TestError(t *testing.T) {
@@ -17,3 +32,139 @@ func TestError(t *testing.T) {
 	assert.Equal(t, "Where is it ?", err.ErrorMessage())
 	assert.Equal(t, "404:Where is it ?", err.Error())
 }
+
+func TestHTTPErrorIsComparable(t *testing.T) {
+	var err error = HTTPError{StatusCode: http.StatusNotFound, Message: "Where is it ?"}
+	assert.True(t, err == HTTPError{StatusCode: http.StatusNotFound, Message: "Where is it ?"})
+	assert.False(t, err == HTTPError{StatusCode: http.StatusConflict})
+	assert.Nil(t, HTTPError{}.Header())
+}
+
+func TestHTTPErrorIs(t *testing.T) {
+	var notFound = HTTPError{StatusCode: http.StatusNotFound, Message: "Where is it ?"}
+	assert.True(t, errors.Is(notFound, ErrNotFound))
+	assert.True(t, errors.Is(notFound, ErrClientError))
+	assert.False(t, errors.Is(notFound, ErrServerError))
+	assert.False(t, errors.Is(notFound, ErrUnauthorized))
+	assert.False(t, errors.Is(notFound, ErrTimeout))
+
+	var wrapped = pkgerrors.Wrap(HTTPError{StatusCode: http.StatusBadGateway}, "context")
+	assert.True(t, errors.Is(wrapped, ErrServerError))
+	assert.False(t, errors.Is(wrapped, ErrClientError))
+
+	var problem = ProblemError{HTTPError: HTTPError{StatusCode: http.StatusConflict}}
+	assert.True(t, errors.Is(pkgerrors.Wrap(problem, "context"), ErrConflict))
+
+	var kcErr = KeycloakError{HTTPError: HTTPError{StatusCode: http.StatusUnauthorized}, ErrorCode: "invalid_grant"}
+	assert.True(t, errors.Is(kcErr, ErrUnauthorized))
+	var httpErr HTTPError
+	assert.True(t, errors.As(pkgerrors.Wrap(kcErr, "context"), &httpErr))
+	assert.Equal(t, http.StatusUnauthorized, httpErr.StatusCode)
+
+	// Targets which are not sentinel errors are compared without requiring them to be hashable
+	type detailedError struct {
+		error
+		Details []string
+	}
+	assert.False(t, errors.Is(notFound, detailedError{error: ErrNotFound}))
+	assert.False(t, errors.Is(notFound, HTTPError{StatusCode: http.StatusNotFound}))
+	assert.False(t, errors.Is(kcErr, detailedError{error: ErrUnauthorized}))
+}
+
+func TestTransportErrorIs(t *testing.T) {
+	var refused = &transportError{err: &url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}}
+	assert.True(t, errors.Is(refused, ErrConnection))
+	assert.False(t, errors.Is(refused, ErrTimeout))
+	assert.True(t, errors.Is(refused, syscall.ECONNREFUSED))
+
+	var timeout = pkgerrors.Wrap(&transportError{err: &url.Error{Op: "Get", URL: "http://localhost", Err: context.DeadlineExceeded}}, MsgErrCannotObtain)
+	assert.True(t, errors.Is(timeout, ErrTimeout))
+	assert.False(t, errors.Is(timeout, ErrConnection))
+	assert.False(t, errors.Is(timeout, ErrClientError))
+}
+
+func TestTimeout(t *testing.T) {
+	var unblock = make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	var client, _ = New(ts.URL, 20*time.Millisecond)
+	var err = client.Get(nil)
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.False(t, errors.Is(err, ErrConnection))
+
+	t.Run("Context deadline", func(t *testing.T) {
+		var client, _ = New(ts.URL, time.Minute)
+		var ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		var err = client.GetCtx(ctx, nil)
+		assert.True(t, strings.HasPrefix(err.Error(), MsgErrCanceled+"."+PrmRequest))
+		assert.True(t, errors.Is(err, ErrTimeout))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.False(t, errors.Is(err, ErrConnection))
+	})
+	t.Run("Context canceled", func(t *testing.T) {
+		var ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		var err = client.GetCtx(ctx, nil)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.False(t, errors.Is(err, ErrTimeout))
+	})
+}
+
+func TestHTTPErrorRequestDetails(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+
+	r := mux.NewRouter()
+	r.Handle("/users", mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = New(ts.URL, time.Minute)
+
+	mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-123")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Not Found"))
+	})
+	var err = client.Get(nil, urlplugin.Path("/users"), query.Set("access_token", "secret-token"))
+
+	var httpErr HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.MethodGet, httpErr.Method)
+	assert.Equal(t, ts.URL+"/users?access_token=REDACTED", httpErr.URL)
+	assert.Equal(t, "req-123", httpErr.RequestID)
+	assert.Equal(t, "req-123", httpErr.Header().Get("X-Request-ID"))
+	assert.True(t, httpErr.Duration > 0)
+	assert.Equal(t, "GET "+ts.URL+"/users?access_token=REDACTED: 404:Not Found", err.Error())
+	assert.NotContains(t, fmt.Sprintf("%+v", err), "secret-token")
+	assert.Contains(t, fmt.Sprintf("%+v", err), "requestID=req-123 duration=")
+	assert.Equal(t, err.Error(), fmt.Sprintf("%v", err))
+}
+
+func TestTransportErrorRequestDetails(t *testing.T) {
+	var client, _ = New("http://localhost:19766", time.Minute)
+	var err = client.Get(nil, urlplugin.Path("/users"), query.Set("access_token", "secret-token"))
+	assert.True(t, errors.Is(err, ErrConnection))
+	assert.Contains(t, err.Error(), "/users?access_token=REDACTED")
+	assert.NotContains(t, fmt.Sprintf("%v", err), "secret-token")
+	assert.NotContains(t, fmt.Sprintf("%+v", err), "secret-token")
+}
+
+func TestRequestIDFromRequest(t *testing.T) {
+	var resp = createErrorResponse(http.StatusNotFound, "text/plain", "")
+	assert.Equal(t, "", resp.RequestID())
+
+	resp.gentlemanResponse.RawRequest = &http.Request{Header: http.Header{"X-Request-Id": []string{"sent-id"}}}
+	assert.Equal(t, "sent-id", resp.RequestID())
+}
@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"strings"
+
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// DoJSON sends a HTTP request using the given method and decodes the response into a value of type T, using the decoder of the
+// client registered for the response media type. The body is decoded while it is received. An empty response body gives the zero value of T
+func DoJSON[T any](ctx context.Context, c RestClientContext, method string, plugins ...plugin.Plugin) (T, error) {
+	var value T
+	var err = c.Stream(ctx, method, func(resp *Response) error {
+		return resp.Decode(&value)
+	}, plugins...)
+	return value, err
+}
+
+// GetJSON is a HTTP GET method decoding the JSON response into a value of type T
+func GetJSON[T any](c RestClientContext, plugins ...plugin.Plugin) (T, error) {
+	return DoJSON[T](context.Background(), c, http.MethodGet, plugins...)
+}
+
+// GetJSONCtx is a HTTP GET method bound to the context ctx decoding the JSON response into a value of type T
+func GetJSONCtx[T any](ctx context.Context, c RestClientContext, plugins ...plugin.Plugin) (T, error) {
+	return DoJSON[T](ctx, c, http.MethodGet, plugins...)
+}
+
+// PostJSON is a HTTP POST method decoding the JSON response into a value of type T
+func PostJSON[T any](c RestClientContext, plugins ...plugin.Plugin) (T, error) {
+	return DoJSON[T](context.Background(), c, http.MethodPost, plugins...)
+}
+
+// PostJSONCtx is a HTTP POST method bound to the context ctx decoding the JSON response into a value of type T
+func PostJSONCtx[T any](ctx context.Context, c RestClientContext, plugins ...plugin.Plugin) (T, error) {
+	return DoJSON[T](ctx, c, http.MethodPost, plugins...)
+}
+
+// MediaTypeNDJSON is the media type of the newline delimited JSON streams
+const MediaTypeNDJSON = "application/x-ndjson"
+
+// errStopIteration is returned by the response handler of DoJSONSeq when the caller stopped iterating
+var errStopIteration = errors.New("stopIteration")
+
+// DoJSONSeq sends a HTTP request using the given method and iterates over the values of type T contained in the response,
+// which is either a top-level JSON array or a NDJSON stream. The values are decoded while the response body is received.
+// An error ends the iteration. Breaking out of the loop closes the response body. The concurrency slot of the client is held until the iteration ends
+func DoJSONSeq[T any](ctx context.Context, c RestClientContext, method string, plugins ...plugin.Plugin) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var err = c.Stream(ctx, method, func(resp *Response) error {
+			return decodeJSONSeq(resp, yield)
+		}, plugins...)
+		if err != nil && !errors.Is(err, errStopIteration) {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// GetJSONSeq is a HTTP GET method iterating over the values of type T contained in a JSON array or a NDJSON stream
+func GetJSONSeq[T any](c RestClientContext, plugins ...plugin.Plugin) iter.Seq2[T, error] {
+	return DoJSONSeq[T](context.Background(), c, http.MethodGet, plugins...)
+}
+
+// GetJSONSeqCtx is a HTTP GET method bound to the context ctx iterating over the values of type T contained in a JSON array or a NDJSON stream
+func GetJSONSeqCtx[T any](ctx context.Context, c RestClientContext, plugins ...plugin.Plugin) iter.Seq2[T, error] {
+	return DoJSONSeq[T](ctx, c, http.MethodGet, plugins...)
+}
+
+// decodeJSONSeq decodes the values of the response body one by one and passes them to yield. An empty body contains no value
+func decodeJSONSeq[T any](resp *Response, yield func(T, error) bool) error {
+	var reader = bufio.NewReader(resp.Reader())
+	if _, err := peekJSON(reader); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var contentType = resp.Header.Get("Content-Type")
+	var ndjson = mediaTypeOf(contentType) == MediaTypeNDJSON
+	if !ndjson && !isJSONMediaType(contentType) {
+		return fmt.Errorf("%s.%v", MsgErrUnkownHTTPContentType, contentType)
+	}
+
+	var decoder = json.NewDecoder(reader)
+	if !ndjson {
+		if token, err := decoder.Token(); err != nil {
+			return err
+		} else if token != json.Delim('[') {
+			return fmt.Errorf("%s.%s", MsgErrCannotParse, PrmResponse)
+		}
+	}
+	for ndjson || decoder.More() {
+		var value T
+		if err := decoder.Decode(&value); err == io.EOF && ndjson {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !yield(value, nil) {
+			resp.content.abort()
+			return errStopIteration
+		}
+	}
+	var _, err = decoder.Token()
+	return err
+}
+
+// isJSONMediaType is true for application/json and the media types using the +json structured syntax suffix
+func isJSONMediaType(contentType string) bool {
+	var mediaType, _, err = mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,240 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+type sampleEntity struct {
+	Key1 string `json:"key1"`
+	Key2 int    `json:"key2"`
+}
+
+func TestTypedHelpers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = New(ts.URL, time.Minute)
+
+	var respondWith = func(contentType string, body string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(body))
+		}
+	}
+
+	t.Run("GET", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("application/json; charset=utf-8", `{"key1": "value1", "key2": 234}`))
+		var entity, err = GetJSON[sampleEntity](client, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, sampleEntity{Key1: "value1", Key2: 234}, entity)
+	})
+	t.Run("GET slice with context", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("application/vnd.cloudtrust.v2+json", `[{"key1": "a"}, {"key1": "b"}]`))
+		var entities, err = GetJSONCtx[[]sampleEntity](context.Background(), client, url.Path(path))
+		assert.Nil(t, err)
+		assert.Len(t, entities, 2)
+		assert.Equal(t, "b", entities[1].Key1)
+	})
+	t.Run("POST", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			respondWith("application/json", `{"key2": 1}`)(w, r)
+		})
+		var entity, err = PostJSON[*sampleEntity](client, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, entity.Key2)
+	})
+	t.Run("POST without content", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+		var entity, err = PostJSONCtx[*sampleEntity](context.Background(), client, url.Path(path))
+		assert.Nil(t, err)
+		assert.Nil(t, entity)
+	})
+	t.Run("Not a JSON response", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("text/plain", `hello`))
+		var _, err = GetJSON[sampleEntity](client, url.Path(path))
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid JSON for type", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("application/json", `{"key2": "not a number"}`))
+		var _, err = GetJSON[sampleEntity](client, url.Path(path))
+		assert.NotNil(t, err)
+	})
+	t.Run("Error status", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		var _, err = GetJSON[sampleEntity](client, url.Path(path))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusNotFound, Attempts: 1}, withoutRequestDetails(err))
+	})
+	t.Run("Decoder registered for the media type", func(t *testing.T) {
+		var client, _ = NewWithOptions(ts.URL, time.Minute, WithDecoder("application/vnd.cloudtrust.v2+json", func(body io.Reader, data any) error {
+			var envelope struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if err := json.NewDecoder(body).Decode(&envelope); err != nil {
+				return err
+			}
+			return json.Unmarshal(envelope.Data, data)
+		}))
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("application/vnd.cloudtrust.v2+json", `{"data": {"key1": "value1"}}`))
+		var entity, err = GetJSON[sampleEntity](client, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, "value1", entity.Key1)
+	})
+	t.Run("Body too large", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("application/json", `[{"key1": "a"}, {"key1": "b"}]`))
+		var _, err = GetJSON[[]sampleEntity](client, url.Path(path), MaxBodySize(10))
+		assert.True(t, errors.Is(err, ErrBodyTooLarge))
+	})
+}
+
+func TestJSONSeq(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = New(ts.URL, time.Minute)
+
+	var respondWith = func(contentType string, body string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(body))
+		}
+	}
+	var collect = func(seq iter.Seq2[sampleEntity, error]) ([]sampleEntity, error) {
+		var entities []sampleEntity
+		for entity, err := range seq {
+			if err != nil {
+				return entities, err
+			}
+			entities = append(entities, entity)
+		}
+		return entities, nil
+	}
+
+	t.Run("JSON array", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("application/json", ` [{"key1": "a"}, {"key1": "b", "key2": 2}] `))
+		var entities, err = collect(GetJSONSeq[sampleEntity](client, url.Path(path)))
+		assert.Nil(t, err)
+		assert.Equal(t, []sampleEntity{{Key1: "a"}, {Key1: "b", Key2: 2}}, entities)
+	})
+	t.Run("NDJSON", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(MediaTypeNDJSON, "{\"key1\": \"a\"}\n{\"key1\": \"b\"}\n\n{\"key1\": \"c\"}\n"))
+		var entities, err = collect(GetJSONSeqCtx[sampleEntity](context.Background(), client, url.Path(path)))
+		assert.Nil(t, err)
+		assert.Equal(t, []sampleEntity{{Key1: "a"}, {Key1: "b"}, {Key1: "c"}}, entities)
+	})
+	t.Run("Empty body", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		var entities, err = collect(GetJSONSeq[sampleEntity](client, url.Path(path)))
+		assert.Nil(t, err)
+		assert.Nil(t, entities)
+	})
+	t.Run("Early break closes the body", func(t *testing.T) {
+		var disconnected = make(chan struct{})
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			defer close(disconnected)
+			w.Header().Set("Content-Type", MediaTypeNDJSON)
+			for i := 0; ; i++ {
+				if _, err := fmt.Fprintf(w, "{\"key2\": %d}\n", i); err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+		})
+		var count int
+		for entity, err := range GetJSONSeq[sampleEntity](client, url.Path(path)) {
+			assert.Nil(t, err)
+			assert.Equal(t, count, entity.Key2)
+			count++
+			if count == 3 {
+				break
+			}
+		}
+		assert.Equal(t, 3, count)
+		select {
+		case <-disconnected:
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "response body not closed")
+		}
+	})
+	t.Run("Invalid element", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("application/json", `[{"key1": "a"}, {"key2": "not a number"}, {"key1": "c"}]`))
+		var entities, err = collect(GetJSONSeq[sampleEntity](client, url.Path(path)))
+		assert.NotNil(t, err)
+		assert.Equal(t, []sampleEntity{{Key1: "a"}}, entities)
+	})
+	t.Run("Not a JSON array", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("application/json", `{"key1": "a"}`))
+		var _, err = collect(GetJSONSeq[sampleEntity](client, url.Path(path)))
+		assert.NotNil(t, err)
+	})
+	t.Run("Not a JSON response", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith("text/plain", `hello`))
+		var _, err = collect(GetJSONSeq[sampleEntity](client, url.Path(path)))
+		assert.NotNil(t, err)
+	})
+	t.Run("Error status", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		var calls int
+		for _, err := range GetJSONSeq[sampleEntity](client, url.Path(path)) {
+			calls++
+			assert.True(t, errors.Is(err, ErrNotFound))
+		}
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestIsJSONMediaType(t *testing.T) {
+	assert.True(t, isJSONMediaType("application/json"))
+	assert.True(t, isJSONMediaType("application/json; charset=utf-8"))
+	assert.True(t, isJSONMediaType("application/problem+json"))
+	assert.False(t, isJSONMediaType("text/plain"))
+	assert.False(t, isJSONMediaType(""))
+}
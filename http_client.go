@@ -1,8 +1,8 @@
 package httpclient
 
 import (
+	"context"
 	"encoding/json"
-	"strings"
 	"time"
 
 	"fmt"
@@ -21,10 +21,37 @@ type Client struct {
 	apiURL      *url.URL
 	httpClient  *gentleman.Client
 	reqUpdaters []func(*gentleman.Request) (*gentleman.Request, error)
+	retryPolicy RetryPolicy
+
+	breakerSettings *CircuitBreakerSettings
+	breaker         *CircuitBreaker
+	rateLimiter     *rateLimiter
+	bulkhead        bulkhead
+	decoders        mediaTypeRegistry[Decoder]
+	encoders        mediaTypeRegistry[Encoder]
+
+	errorBodyLimit     int
+	errorBodyRedactors []ErrorBodyRedactor
+	maxBodySize        int64
+}
+
+// Option is used to configure a Client when creating it with NewWithOptions
+type Option func(*Client)
+
+// WithRequestUpdaters adds request updaters which are called for each request sent by the client
+func WithRequestUpdaters(reqUpdaters ...func(*gentleman.Request) (*gentleman.Request, error)) Option {
+	return func(c *Client) {
+		c.reqUpdaters = append(c.reqUpdaters, reqUpdaters...)
+	}
 }
 
 // New returns a keycloak client.
 func New(addrAPI string, reqTimeout time.Duration, reqUpdaters ...func(*gentleman.Request) (*gentleman.Request, error)) (*Client, error) {
+	return NewWithOptions(addrAPI, reqTimeout, WithRequestUpdaters(reqUpdaters...))
+}
+
+// NewWithOptions returns a keycloak client configured with the given options.
+func NewWithOptions(addrAPI string, reqTimeout time.Duration, opts ...Option) (*Client, error) {
 	var uAPI *url.URL
 	{
 		var err error
@@ -41,15 +68,21 @@ func New(addrAPI string, reqTimeout time.Duration, reqUpdaters ...func(*gentlema
 	}
 
 	var client = &Client{
-		apiURL:      uAPI,
-		httpClient:  httpClient,
-		reqUpdaters: reqUpdaters,
+		apiURL:         uAPI,
+		httpClient:     httpClient,
+		decoders:       defaultDecoders(),
+		encoders:       defaultEncoders(),
+		errorBodyLimit: DefaultErrorBodyLimit,
+	}
+	for _, opt := range opts {
+		opt(client)
 	}
 
 	return client, nil
 }
 
-// applyPlugins apply all the plugins to the request req, apply also includes internal reqUpdaters
+// applyPlugins apply all the plugins to the request req, apply also includes internal reqUpdaters.
+// Request updaters can reach the caller context through req.Context.Request.Context()
 func (c *Client) applyPlugins(req *gentleman.Request, plugins ...plugin.Plugin) (*gentleman.Request, error) {
 	var err error
 	for _, p := range plugins {
@@ -64,142 +97,368 @@ func (c *Client) applyPlugins(req *gentleman.Request, plugins ...plugin.Plugin)
 	return req, nil
 }
 
+// checkError returns the error reported by resp. The error response body is redacted before being parsed and the error message is truncated
 func (c *Client) checkError(resp *internalResponse) error {
-	switch {
-	case resp.StatusCode() == http.StatusUnauthorized:
-		return HTTPError{
-			StatusCode: resp.StatusCode(),
-			Message:    string(resp.Bytes()),
-		}
-	case resp.StatusCode() >= 400:
-		return treatErrorStatus(resp)
-	case resp.StatusCode() >= 200:
+	if resp.StatusCode() >= 200 && resp.StatusCode() < 400 {
 		return nil
-	default:
-		return HTTPError{
-			StatusCode: resp.StatusCode(),
-			Message:    string(resp.Bytes()),
-		}
 	}
+	return c.truncateErrorMessage(parseErrorBody(resp.StatusCode(), resp.GetHeader("Content-Type"), c.errorBody(resp)))
 }
 
-func (c *Client) readContent(resp *internalResponse, data any) (retError error) {
-	defer func() {
-		if err := recover(); err != nil {
-			retError = fmt.Errorf("Unexpected panic. Ensure data is declared with the expected type: %v", err)
-		}
-	}()
+// readContent decodes the response body into data using the decoder registered for the response media type.
+// The decoder reads the body from the connection, without the body being copied in memory first
+func (c *Client) readContent(resp *internalResponse, data any) error {
 	var hdr = resp.GetHeader("Content-Type")
-	switch strings.Split(hdr, ";")[0] {
-	case "application/json":
-		retError = resp.JSON(data)
-	case "text/plain":
-		*(data.(*string)) = resp.String()
-		retError = nil
-	case "text/html":
-		*(data.(*string)) = resp.String()
-		retError = nil
-	case "application/octet-stream", "application/zip", "application/pdf", "text/xml":
-		*(data.(*[]byte)) = resp.Bytes()
-		retError = nil
-	default:
-		if len(resp.Bytes()) == 0 {
-			retError = nil
-		} else {
-			retError = fmt.Errorf("%s.%v", MsgErrUnkownHTTPContentType, hdr)
+	var decoder, ok = c.decoders.lookup(hdr)
+	if !ok {
+		var content, err = resp.read()
+		if err != nil {
+			return err
+		}
+		if len(content) == 0 {
+			return nil
 		}
+		return fmt.Errorf("%s.%v", MsgErrUnkownHTTPContentType, hdr)
 	}
-	return retError
+	return decoder(resp.Reader(), data)
 }
 
-// Get is a HTTP GET method.
-func (c *Client) Get(data any, plugins ...plugin.Plugin) error {
-	var err error
-	var req = c.httpClient.Get()
-	req, err = c.applyPlugins(req, plugins...)
+// do sends a request using the given HTTP method and lets handle process the successful response.
+// The concurrency slot of the client is held until handle returns and the response body is closed afterwards
+func (c *Client) do(ctx context.Context, method string, handle func(*Response) error, plugins ...plugin.Plugin) error {
+	if c.bulkhead != nil {
+		if err := c.bulkhead.acquire(ctx); err != nil {
+			return errors.Wrap(&canceledError{err: err}, MsgErrCanceled+"."+PrmRequest)
+		}
+		defer c.bulkhead.release()
+	}
+
+	var resp, err = c.send(ctx, method, plugins...)
 	if err != nil {
 		return err
 	}
+	var failed = true
+	defer func() {
+		// The body is closed without reading its remaining content when handle failed
+		if failed {
+			resp.content.abort()
+		} else {
+			resp.content.Close()
+		}
+	}()
+	err = handle(resp)
+	// A body read in memory by handle may have been cut by a read failure which is reported instead of the error of handle
+	if resp.content.err != nil {
+		err = resp.content.err
+	}
+	failed = err != nil
+	return err
+}
 
-	var gresp *gentleman.Response
-	{
-		gresp, err = req.Do()
-		if err != nil {
-			return errors.Wrap(err, MsgErrCannotObtain+"."+PrmResponse)
+// send sends a request using the given HTTP method, retrying it according to the client retry policy.
+// The returned error is an HTTPError when the server answered with an error status
+func (c *Client) send(ctx context.Context, method string, plugins ...plugin.Plugin) (*Response, error) {
+	var start = time.Now()
+	var maxAttempts = c.retryPolicy.maxAttempts(method)
+	if !replayable(plugins) {
+		maxAttempts = 1
+	}
+	if maxAttempts > 1 && !hasBodyPlugin(plugins) {
+		// The request body set by other plugins may only be readable once: it is kept in memory to be sent again
+		plugins = append([]plugin.Plugin{(&bodyReplay{}).plugin()}, plugins...)
+	}
+	for attempt := 1; ; attempt++ {
+		var resp, err = c.attempt(ctx, method, plugins...)
+		if err == nil {
+			return buildResponse(c, resp, time.Since(start), attempt), nil
 		}
 
-		var resp = buildInternalResponse(gresp)
-		err = c.checkError(resp)
-		if err != nil {
-			return err
+		var retry bool
+		var delay = c.retryPolicy.backoff(attempt)
+		var tErr *transportError
+		var httpErr HTTPError
+		switch {
+		case errors.As(err, &tErr):
+			retry = c.retryPolicy.retryError(tErr.err)
+			err = errors.Wrap(tErr, MsgErrCannotObtain+"."+PrmResponse)
+		case errors.As(err, &httpErr):
+			retry = c.retryPolicy.retryStatus(httpErr.StatusCode)
+			var retryAfter time.Duration
+			if resp != nil && (httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode == http.StatusServiceUnavailable) {
+				retryAfter, _ = parseRetryAfter(resp.GetHeader("Retry-After"), time.Now())
+			}
+			if retryAfter > 0 {
+				delay = retryAfter
+				retry = retry && c.retryPolicy.canWait(ctx, delay)
+			}
+			err = updateHTTPError(err, func(e *HTTPError) {
+				e.Method = method
+				e.Duration = time.Since(start)
+				e.Attempts = attempt
+				e.RetryAfter = retryAfter
+				// resp is nil when the HTTPError is returned by a request updater
+				if resp != nil {
+					var header = resp.Header()
+					e.header = &header
+					e.RequestID = resp.RequestID()
+					if req := resp.Request(); req != nil && req.URL != nil {
+						e.URL = SanitizeURL(req.URL)
+					}
+				}
+			})
+		}
+		if !retry || attempt >= maxAttempts {
+			return nil, err
+		}
+
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return nil, errors.Wrap(&canceledError{err: sleepErr}, MsgErrCanceled+"."+PrmRequest)
 		}
-		return c.readContent(resp, data)
 	}
 }
 
-// Post is a HTTP POST method
-func (c *Client) Post(data any, plugins ...plugin.Plugin) (string, error) {
+// attempt sends a single request and checks its response status. The request is guarded by the client circuit breaker
+func (c *Client) attempt(ctx context.Context, method string, plugins ...plugin.Plugin) (*internalResponse, error) {
+	if c.rateLimiter != nil {
+		if err := c.rateLimiter.wait(ctx); err != nil {
+			return nil, errors.Wrap(&canceledError{err: err}, MsgErrCanceled+"."+PrmRequest)
+		}
+	}
+	if c.breaker != nil {
+		if err := c.breaker.before(); err != nil {
+			return nil, err
+		}
+	}
+
+	var resp, err = c.exchange(ctx, method, plugins...)
+	if err == nil {
+		err = c.checkError(resp)
+	}
+
+	if c.breaker != nil {
+		c.breaker.after(circuitOutcomeOf(err))
+	}
+	return resp, err
+}
+
+// exchange applies the plugins to a new request, attaches ctx to it and dispatches it.
+// Failures occurring while dispatching the request are returned as a *transportError
+func (c *Client) exchange(ctx context.Context, method string, plugins ...plugin.Plugin) (*internalResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(&canceledError{err: err}, MsgErrCanceled+"."+PrmRequest)
+	}
+	var req = c.httpClient.Request().Method(method)
+	req.Context.SetCancelContext(ctx)
+
 	var err error
-	var req = c.httpClient.Post()
 	req, err = c.applyPlugins(req, plugins...)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var gresp *gentleman.Response
-	{
-		gresp, err = req.Do()
-		if err != nil {
-			return "", errors.Wrap(err, MsgErrCannotObtain+"."+PrmResponse)
+	gresp, err = req.Do()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.Wrap(&canceledError{err: ctx.Err()}, MsgErrCanceled+"."+PrmRequest)
 		}
-		var resp = buildInternalResponse(gresp)
-
-		err = c.checkError(resp)
-		if err != nil {
-			return "", err
+		// The URL of the error is logged: its secrets are redacted
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && req.Context.Request.URL != nil {
+			urlErr.URL = SanitizeURL(req.Context.Request.URL)
 		}
-		return resp.GetHeader("Location"), c.readContent(resp, data)
+		return nil, &transportError{err: err}
 	}
+	gresp.RawResponse.Body = newLimitedBody(gresp.RawResponse.Body, c.maxBodySizeOf(gresp.Context))
+
+	return buildInternalResponse(gresp), nil
+}
+
+// Do sends a HTTP request using the given method and returns the response with its body fully read.
+// Responses with an error status are reported as an HTTPError like for the other methods
+func (c *Client) Do(ctx context.Context, method string, plugins ...plugin.Plugin) (*Response, error) {
+	var response *Response
+	var err = c.do(ctx, method, func(resp *Response) error {
+		response = resp
+		return resp.content.load()
+	}, plugins...)
+	return response, err
+}
+
+// Stream sends a HTTP request using the given method and lets handle read the response body while it is received.
+// Responses with an error status are reported as an HTTPError without calling handle. The body is closed when handle returns
+func (c *Client) Stream(ctx context.Context, method string, handle func(*Response) error, plugins ...plugin.Plugin) error {
+	return c.do(ctx, method, handle, plugins...)
+}
+
+// Get is a HTTP GET method.
+func (c *Client) Get(data any, plugins ...plugin.Plugin) error {
+	return c.GetCtx(context.Background(), data, plugins...)
+}
+
+// GetCtx is a HTTP GET method bound to the context ctx.
+func (c *Client) GetCtx(ctx context.Context, data any, plugins ...plugin.Plugin) error {
+	return c.do(ctx, http.MethodGet, func(resp *Response) error {
+		return c.readContent(resp.content, data)
+	}, plugins...)
+}
+
+// Post is a HTTP POST method
+func (c *Client) Post(data any, plugins ...plugin.Plugin) (string, error) {
+	return c.PostCtx(context.Background(), data, plugins...)
+}
+
+// PostCtx is a HTTP POST method bound to the context ctx.
+func (c *Client) PostCtx(ctx context.Context, data any, plugins ...plugin.Plugin) (string, error) {
+	return c.doForContent(ctx, http.MethodPost, data, plugins...)
 }
 
 // Delete is a HTTP DELETE method
 func (c *Client) Delete(plugins ...plugin.Plugin) error {
-	var err error
-	var req = c.httpClient.Delete()
-	req, err = c.applyPlugins(req, plugins...)
-	if err != nil {
-		return err
-	}
+	return c.DeleteCtx(context.Background(), plugins...)
+}
 
-	var resp *gentleman.Response
-	{
-		resp, err = req.Do()
-		if err != nil {
-			return errors.Wrap(err, MsgErrCannotObtain+"."+PrmResponse)
-		}
+// DeleteCtx is a HTTP DELETE method bound to the context ctx.
+func (c *Client) DeleteCtx(ctx context.Context, plugins ...plugin.Plugin) error {
+	return c.do(ctx, http.MethodDelete, discardContent, plugins...)
+}
 
-		return c.checkError(buildInternalResponse(resp))
-	}
+// DeleteData is a HTTP DELETE method which reads the response content into data. It returns the Location header
+func (c *Client) DeleteData(data any, plugins ...plugin.Plugin) (string, error) {
+	return c.DeleteDataCtx(context.Background(), data, plugins...)
+}
+
+// DeleteDataCtx is a HTTP DELETE method bound to the context ctx which reads the response content into data. It returns the Location header
+func (c *Client) DeleteDataCtx(ctx context.Context, data any, plugins ...plugin.Plugin) (string, error) {
+	return c.doForContent(ctx, http.MethodDelete, data, plugins...)
 }
 
 // Put is a HTTP PUT method
 func (c *Client) Put(plugins ...plugin.Plugin) error {
-	var err error
-	var req = c.httpClient.Put()
-	req, err = c.applyPlugins(req, plugins...)
-	if err != nil {
-		return err
-	}
+	return c.PutCtx(context.Background(), plugins...)
+}
 
-	var resp *gentleman.Response
-	{
-		resp, err = req.Do()
+// PutCtx is a HTTP PUT method bound to the context ctx.
+func (c *Client) PutCtx(ctx context.Context, plugins ...plugin.Plugin) error {
+	return c.do(ctx, http.MethodPut, discardContent, plugins...)
+}
+
+// PutData is a HTTP PUT method which reads the response content into data. It returns the Location header
+func (c *Client) PutData(data any, plugins ...plugin.Plugin) (string, error) {
+	return c.PutDataCtx(context.Background(), data, plugins...)
+}
+
+// PutDataCtx is a HTTP PUT method bound to the context ctx which reads the response content into data. It returns the Location header
+func (c *Client) PutDataCtx(ctx context.Context, data any, plugins ...plugin.Plugin) (string, error) {
+	return c.doForContent(ctx, http.MethodPut, data, plugins...)
+}
+
+// Patch is a HTTP PATCH method
+func (c *Client) Patch(data any, plugins ...plugin.Plugin) error {
+	return c.PatchCtx(context.Background(), data, plugins...)
+}
+
+// PatchCtx is a HTTP PATCH method bound to the context ctx.
+func (c *Client) PatchCtx(ctx context.Context, data any, plugins ...plugin.Plugin) error {
+	return c.do(ctx, http.MethodPatch, func(resp *Response) error {
+		return c.readContent(resp.content, data)
+	}, plugins...)
+}
+
+// Head is a HTTP HEAD method. It returns the response headers
+func (c *Client) Head(plugins ...plugin.Plugin) (http.Header, error) {
+	return c.HeadCtx(context.Background(), plugins...)
+}
+
+// HeadCtx is a HTTP HEAD method bound to the context ctx. It returns the response headers
+func (c *Client) HeadCtx(ctx context.Context, plugins ...plugin.Plugin) (http.Header, error) {
+	return c.doForHeaders(ctx, http.MethodHead, plugins...)
+}
+
+// Options is a HTTP OPTIONS method. It returns the response headers
+func (c *Client) Options(plugins ...plugin.Plugin) (http.Header, error) {
+	return c.OptionsCtx(context.Background(), plugins...)
+}
+
+// OptionsCtx is a HTTP OPTIONS method bound to the context ctx. It returns the response headers
+func (c *Client) OptionsCtx(ctx context.Context, plugins ...plugin.Plugin) (http.Header, error) {
+	return c.doForHeaders(ctx, http.MethodOptions, plugins...)
+}
+
+// PostBody is a HTTP POST method sending in as request body and reading the response content into out. It returns the Location header.
+// The request body is encoded as JSON unless another media type is given with the ContentType plugin
+func (c *Client) PostBody(in, out any, plugins ...plugin.Plugin) (string, error) {
+	return c.PostBodyCtx(context.Background(), in, out, plugins...)
+}
+
+// PostBodyCtx is a HTTP POST method bound to the context ctx sending in as request body and reading the response content into out. It returns the Location header
+func (c *Client) PostBodyCtx(ctx context.Context, in, out any, plugins ...plugin.Plugin) (string, error) {
+	return c.doWithBody(ctx, http.MethodPost, in, out, plugins...)
+}
+
+// PutBody is a HTTP PUT method sending in as request body and reading the response content into out. It returns the Location header.
+// The request body is encoded as JSON unless another media type is given with the ContentType plugin
+func (c *Client) PutBody(in, out any, plugins ...plugin.Plugin) (string, error) {
+	return c.PutBodyCtx(context.Background(), in, out, plugins...)
+}
+
+// PutBodyCtx is a HTTP PUT method bound to the context ctx sending in as request body and reading the response content into out. It returns the Location header
+func (c *Client) PutBodyCtx(ctx context.Context, in, out any, plugins ...plugin.Plugin) (string, error) {
+	return c.doWithBody(ctx, http.MethodPut, in, out, plugins...)
+}
+
+// PatchBody is a HTTP PATCH method sending in as request body and reading the response content into out.
+// The request body is encoded as JSON unless another media type is given with the ContentType plugin
+func (c *Client) PatchBody(in, out any, plugins ...plugin.Plugin) error {
+	return c.PatchBodyCtx(context.Background(), in, out, plugins...)
+}
+
+// PatchBodyCtx is a HTTP PATCH method bound to the context ctx sending in as request body and reading the response content into out
+func (c *Client) PatchBodyCtx(ctx context.Context, in, out any, plugins ...plugin.Plugin) error {
+	var _, err = c.doWithBody(ctx, http.MethodPatch, in, out, plugins...)
+	return err
+}
+
+// doWithBody encodes in as request body, sends the request and reads the response content into out. A nil in sends no request body
+func (c *Client) doWithBody(ctx context.Context, method string, in, out any, plugins ...plugin.Plugin) (string, error) {
+	if in != nil {
+		var body, err = c.encodeBody(in, plugins)
 		if err != nil {
-			return errors.Wrap(err, MsgErrCannotObtain+"."+PrmResponse)
+			return "", err
 		}
-
-		return c.checkError(buildInternalResponse(resp))
+		plugins = append(plugins[:len(plugins):len(plugins)], body)
 	}
+	return c.doForContent(ctx, method, out, plugins...)
+}
+
+func (c *Client) doForContent(ctx context.Context, method string, data any, plugins ...plugin.Plugin) (string, error) {
+	var location string
+	var err = c.do(ctx, method, func(resp *Response) error {
+		location = resp.Header.Get("Location")
+		return c.readContent(resp.content, data)
+	}, plugins...)
+	return location, err
+}
+
+func (c *Client) doForHeaders(ctx context.Context, method string, plugins ...plugin.Plugin) (http.Header, error) {
+	var header http.Header
+	var err = c.do(ctx, method, func(resp *Response) error {
+		header = resp.Header
+		return nil
+	}, plugins...)
+	return header, err
+}
+
+// discardContent is the response handler of the methods which ignore the response body
+func discardContent(_ *Response) error {
+	return nil
+}
+
+// withCircuitBreaker returns a copy of the client guarded by the circuit breaker cb
+func (c *Client) withCircuitBreaker(cb *CircuitBreaker) *Client {
+	var scoped = *c
+	scoped.breaker = cb
+	return &scoped
 }
 
 // CreateQueryPlugins create query parameters with the key values paramKV.
@@ -213,17 +472,50 @@ func CreateQueryPlugins(paramKV ...string) []plugin.Plugin {
 	return plugins
 }
 
-func treatErrorStatus(resp *internalResponse) error {
-	var response map[string]any
-	err := json.Unmarshal(resp.Bytes(), &response)
-	if message, ok := response["errorMessage"]; ok && err == nil {
+// parseErrorBody builds the error reported by a response with the given status code, content type and body
+func parseErrorBody(statusCode int, contentType string, body []byte) error {
+	switch {
+	case statusCode >= 400 && mediaTypeOf(contentType) == MediaTypeProblemJSON:
+		if problem, ok := parseProblem(statusCode, body); ok {
+			return problem
+		}
+		return treatErrorStatus(statusCode, body)
+	case statusCode == http.StatusUnauthorized:
+		var response map[string]any
+		if err := json.Unmarshal(body, &response); err == nil {
+			if kcErr, ok := parseKeycloakError(statusCode, response); ok {
+				return kcErr
+			}
+		}
 		return HTTPError{
-			StatusCode: resp.StatusCode(),
-			Message:    message.(string),
+			StatusCode: statusCode,
+			Message:    string(body),
+		}
+	case statusCode >= 400:
+		return treatErrorStatus(statusCode, body)
+	default:
+		return HTTPError{
+			StatusCode: statusCode,
+			Message:    string(body),
+		}
+	}
+}
+
+func treatErrorStatus(statusCode int, body []byte) error {
+	var response map[string]any
+	if err := json.Unmarshal(body, &response); err == nil {
+		if kcErr, ok := parseKeycloakError(statusCode, response); ok {
+			return kcErr
+		}
+		if message, ok := response["errorMessage"].(string); ok {
+			return HTTPError{
+				StatusCode: statusCode,
+				Message:    message,
+			}
 		}
 	}
 	return HTTPError{
-		StatusCode: resp.StatusCode(),
-		Message:    string(resp.Bytes()),
+		StatusCode: statusCode,
+		Message:    string(body),
 	}
 }
@@ -1,9 +1,12 @@
 package httpclient
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -15,6 +18,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"gopkg.in/h2non/gentleman.v2/plugins/body"
+	"gopkg.in/h2non/gentleman.v2/plugins/headers"
 	"gopkg.in/h2non/gentleman.v2/plugins/url"
 )
 
@@ -34,6 +38,7 @@ func TestNoResponse(t *testing.T) {
 	t.Run("Get", func(t *testing.T) {
 		err = client.Get(nil, url.Path("/any/path/to/target"))
 		assert.True(t, strings.Contains(err.Error(), "cannotObtain.response"))
+		assert.True(t, errors.Is(err, ErrConnection))
 	})
 	t.Run("Post", func(t *testing.T) {
 		_, err = client.Post(nil, url.Path("/any/path/to/target"))
@@ -76,6 +81,23 @@ func TestRequestUpdaterFails(t *testing.T) {
 	})
 }
 
+func TestRequestUpdaterReturnsHTTPError(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusServiceUnavailable} {
+		var client, _ = NewWithOptions("http://localhost", time.Minute, WithRetryPolicy(testRetryPolicy()),
+			WithRequestUpdaters(func(r *gentleman.Request) (*gentleman.Request, error) {
+				return nil, HTTPError{StatusCode: status}
+			}))
+		var err = client.Get(nil, url.Path("/any/path/to/target"))
+
+		var httpErr HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, status, httpErr.StatusCode)
+		assert.Equal(t, http.MethodGet, httpErr.Method)
+		assert.Nil(t, httpErr.Header())
+		assert.Equal(t, time.Duration(0), httpErr.RetryAfter)
+	}
+}
+
 func TestProcessResponse(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -96,6 +118,7 @@ func TestProcessResponse(t *testing.T) {
 	var expectedError = HTTPError{
 		StatusCode: http.StatusUnauthorized,
 		Message:    "error message",
+		Attempts:   1,
 	}
 	mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).DoAndReturn(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
@@ -105,26 +128,27 @@ func TestProcessResponse(t *testing.T) {
 	t.Run("GET-Unauthorized", func(t *testing.T) {
 		var resp string
 		var err = client.Get(&resp, url.Path(path))
-		assert.Equal(t, expectedError, err)
+		assert.Equal(t, expectedError, withoutRequestDetails(err))
 		assert.Equal(t, "", resp)
 	})
 	t.Run("POST-Unauthorized", func(t *testing.T) {
 		var resp string
 		var _, err = client.Post(&resp, url.Path(path))
-		assert.Equal(t, expectedError, err)
+		assert.Equal(t, expectedError, withoutRequestDetails(err))
 	})
 	t.Run("PUT-Unauthorized", func(t *testing.T) {
 		var err = client.Put(url.Path(path))
-		assert.Equal(t, expectedError, err)
+		assert.Equal(t, expectedError, withoutRequestDetails(err))
 	})
 	t.Run("DELETE-Unauthorized", func(t *testing.T) {
 		var err = client.Delete(url.Path(path))
-		assert.Equal(t, expectedError, err)
+		assert.Equal(t, expectedError, withoutRequestDetails(err))
 	})
 
 	expectedError = HTTPError{
 		StatusCode: http.StatusBadRequest,
 		Message:    "error message",
+		Attempts:   1,
 	}
 	mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).DoAndReturn(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
@@ -134,21 +158,21 @@ func TestProcessResponse(t *testing.T) {
 	t.Run("GET-Internal server error", func(t *testing.T) {
 		var resp string
 		var err = client.Get(&resp, url.Path(path))
-		assert.Equal(t, expectedError, err)
+		assert.Equal(t, expectedError, withoutRequestDetails(err))
 		assert.Equal(t, "", resp)
 	})
 	t.Run("POST-Internal server error", func(t *testing.T) {
 		var resp string
 		var _, err = client.Post(&resp, url.Path(path))
-		assert.Equal(t, expectedError, err)
+		assert.Equal(t, expectedError, withoutRequestDetails(err))
 	})
 	t.Run("PUT-Internal server error", func(t *testing.T) {
 		var err = client.Put(url.Path(path))
-		assert.Equal(t, expectedError, err)
+		assert.Equal(t, expectedError, withoutRequestDetails(err))
 	})
 	t.Run("DELETE-Internal server error (json error message)", func(t *testing.T) {
 		var err = client.Delete(url.Path(path))
-		assert.Equal(t, expectedError, err)
+		assert.Equal(t, expectedError, withoutRequestDetails(err))
 	})
 
 	mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).DoAndReturn(func(w http.ResponseWriter, r *http.Request) {
@@ -158,7 +182,7 @@ func TestProcessResponse(t *testing.T) {
 	})
 	t.Run("DELETE-Internal server error (plain text error message)", func(t *testing.T) {
 		var err = client.Delete(url.Path(path))
-		assert.Equal(t, expectedError, err)
+		assert.Equal(t, expectedError, withoutRequestDetails(err))
 	})
 
 	mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).DoAndReturn(func(w http.ResponseWriter, r *http.Request) {
@@ -241,6 +265,122 @@ func TestProcessResponse(t *testing.T) {
 	})
 }
 
+func TestPutAndDeleteWithData(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = New(ts.URL, time.Minute)
+
+	mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).DoAndReturn(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "the location")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"method": "` + r.Method + `"}`))
+	}).Times(2)
+	t.Run("PUT", func(t *testing.T) {
+		var resp map[string]string
+		var location, err = client.PutData(&resp, url.Path(path), body.String("content"))
+		assert.Nil(t, err)
+		assert.Equal(t, "the location", location)
+		assert.Equal(t, http.MethodPut, resp["method"])
+	})
+	t.Run("DELETE", func(t *testing.T) {
+		var resp map[string]string
+		var location, err = client.DeleteData(&resp, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, "the location", location)
+		assert.Equal(t, http.MethodDelete, resp["method"])
+	})
+
+	mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).DoAndReturn(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	t.Run("No content", func(t *testing.T) {
+		var resp map[string]string
+		var _, err = client.DeleteData(&resp, url.Path(path))
+		assert.Nil(t, err)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestPatchHeadOptions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = New(ts.URL, time.Minute)
+
+	t.Run("PATCH", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.Equal(t, "application/merge-patch+json", r.Header.Get("Content-Type"))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"key1": "patched"}`))
+		})
+		var resp map[string]any
+		var err = client.Patch(&resp, url.Path(path), body.String(`{"key1": "patched"}`), headers.Set("Content-Type", "application/merge-patch+json"))
+		assert.Nil(t, err)
+		assert.Equal(t, "patched", resp["key1"])
+	})
+	t.Run("HEAD", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodHead, r.Method)
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(http.StatusOK)
+		})
+		var header, err = client.Head(url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, `"v1"`, header.Get("ETag"))
+	})
+	t.Run("HEAD-Not found", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		var header, err = client.Head(url.Path(path))
+		assert.Nil(t, header)
+		assert.Equal(t, HTTPError{StatusCode: http.StatusNotFound, Message: "", Attempts: 1}, withoutRequestDetails(err))
+	})
+	t.Run("OPTIONS", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodOptions, r.Method)
+			w.Header().Set("Allow", "GET, PUT, PATCH")
+			w.WriteHeader(http.StatusNoContent)
+		})
+		var header, err = client.Options(url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, "GET, PUT, PATCH", header.Get("Allow"))
+	})
+}
+
+// withoutRequestDetails clears the request details of the HTTPError carried by err, to compare it with an expected value
+func withoutRequestDetails(err error) error {
+	return updateHTTPError(err, func(e *HTTPError) {
+		e.Method = ""
+		e.URL = ""
+		e.header = nil
+		e.RequestID = ""
+		e.Duration = 0
+	})
+}
+
 func createResponse(mimeType string, text string) *internalResponse {
 	var resp = buildInternalResponse(&gentleman.Response{
 		Ok:          false,
@@ -318,3 +458,159 @@ func TestReadContent(t *testing.T) {
 		})
 	})
 }
+
+func TestBodyClosedWhenHandlerFails(t *testing.T) {
+	var release = make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"key1": value1, `))
+		w.(http.Flusher).Flush()
+		// The rest of the body is only sent once the test ends
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	var client, _ = New(ts.URL, time.Minute)
+
+	t.Run("Stream", func(t *testing.T) {
+		var start = time.Now()
+		var expectedError = errors.New("handler failure")
+		var err = client.Stream(context.Background(), http.MethodGet, func(resp *Response) error {
+			resp.Reader().Read(make([]byte, 10))
+			return expectedError
+		})
+		assert.Equal(t, expectedError, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+	t.Run("Get", func(t *testing.T) {
+		var start = time.Now()
+		var data string
+		var err = client.Get(&data)
+		assert.NotNil(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestContext(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	type ctxKey struct{}
+	var updaterValue any
+	var client, _ = New(ts.URL, time.Minute, func(r *gentleman.Request) (*gentleman.Request, error) {
+		updaterValue = r.Context.Request.Context().Value(ctxKey{})
+		return r, nil
+	})
+
+	t.Run("Context already canceled", func(t *testing.T) {
+		var ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		var err = client.GetCtx(ctx, nil, url.Path(path))
+		assert.True(t, errors.Is(err, context.Canceled))
+		_, err = client.PostCtx(ctx, nil, url.Path(path))
+		assert.True(t, errors.Is(err, context.Canceled))
+		err = client.PutCtx(ctx, url.Path(path))
+		assert.True(t, errors.Is(err, context.Canceled))
+		err = client.DeleteCtx(ctx, url.Path(path))
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("Deadline exceeded during the call", func(t *testing.T) {
+		var unblock = make(chan struct{})
+		defer close(unblock)
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-unblock:
+			case <-r.Context().Done():
+			}
+		})
+		var ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		var err = client.GetCtx(ctx, nil, url.Path(path))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.True(t, strings.HasPrefix(err.Error(), MsgErrCanceled+"."+PrmRequest))
+	})
+
+	t.Run("Context reaches request updaters", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		var ctx = context.WithValue(context.Background(), ctxKey{}, "trace-id")
+		var err = client.DeleteCtx(ctx, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, "trace-id", updaterValue)
+	})
+}
+
+func BenchmarkReadContentJSON(b *testing.B) {
+	type user struct {
+		ID         string            `json:"id"`
+		Username   string            `json:"username"`
+		Email      string            `json:"email"`
+		Enabled    bool              `json:"enabled"`
+		Attributes map[string]string `json:"attributes"`
+	}
+	var users = make([]user, 10000)
+	for i := range users {
+		users[i] = user{ID: strconv.Itoa(i), Username: "user" + strconv.Itoa(i), Email: "user" + strconv.Itoa(i) + "@example.com",
+			Enabled: true, Attributes: map[string]string{"locale": "en"}}
+	}
+	var payload, _ = json.Marshal(users)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(payload)
+	}))
+	defer ts.Close()
+
+	var client, _ = New(ts.URL, time.Minute)
+
+	b.Run("Streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(payload)))
+		for b.Loop() {
+			var result []user
+			if err := client.Get(&result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Streamed element by element", func(b *testing.B) {
+		var client, _ = NewWithOptions(ts.URL, time.Minute, WithDecoder(MediaTypeJSON, DecodeJSONArray))
+		b.ReportAllocs()
+		b.SetBytes(int64(len(payload)))
+		for b.Loop() {
+			var result []user
+			if err := client.Get(&result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(payload)))
+		for b.Loop() {
+			var resp, err = client.Do(context.Background(), http.MethodGet)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var result []user
+			if err = json.Unmarshal(resp.Bytes(), &result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
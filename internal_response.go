@@ -1,15 +1,24 @@
 package httpclient
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
+	"net/http"
 
+	"github.com/pkg/errors"
 	"gopkg.in/h2non/gentleman.v2"
 )
 
+// maxDrainSize is the maximum number of bytes of an unread response body which are discarded before the body is closed
+const maxDrainSize = 64 << 10
+
 // Internal Response is the only way to let ReadContent be testable
 type internalResponse struct {
 	gentlemanResponse *gentleman.Response
 	bytes             []byte
+	loaded            bool
+	err               error
 }
 
 func buildInternalResponse(resp *gentleman.Response) *internalResponse {
@@ -27,11 +36,69 @@ func (ir *internalResponse) GetHeader(name string) string {
 	return ir.gentlemanResponse.Header.Get(name)
 }
 
+func (ir *internalResponse) Header() http.Header {
+	return ir.gentlemanResponse.Header
+}
+
+// Request returns the request which was sent to obtain the response
+func (ir *internalResponse) Request() *http.Request {
+	return ir.gentlemanResponse.RawRequest
+}
+
+// RequestID returns the X-Request-ID of the response or, when the server did not provide it, the one of the request
+func (ir *internalResponse) RequestID() string {
+	if requestID := ir.GetHeader(HeaderRequestID); requestID != "" {
+		return requestID
+	}
+	if req := ir.Request(); req != nil {
+		return req.Header.Get(HeaderRequestID)
+	}
+	return ""
+}
+
+// Bytes reads the whole response body in memory. It returns nil when the body could not be read completely
 func (ir *internalResponse) Bytes() []byte {
-	if ir.bytes == nil {
+	var content, err = ir.read()
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+// read reads the whole response body in memory. When reading fails, it returns the content read before the failure along with the error
+func (ir *internalResponse) read() ([]byte, error) {
+	if ir.bytes == nil && !ir.loaded {
 		ir.bytes = ir.gentlemanResponse.Bytes()
+		ir.loaded = true
+		if ir.gentlemanResponse.Error != nil {
+			ir.err = errors.Wrap(ir.gentlemanResponse.Error, MsgErrCannotObtain+"."+PrmResponse)
+		}
 	}
-	return ir.bytes
+	return ir.bytes, ir.err
+}
+
+// readAtMost reads at most limit bytes of the response body, keeping the content read before a failure. The body is then closed
+// without reading its remaining content
+func (ir *internalResponse) readAtMost(limit int64) []byte {
+	var content []byte
+	if ir.bytes != nil || ir.loaded || ir.gentlemanResponse.RawResponse.Body == nil {
+		content, _ = ir.read()
+	} else {
+		content, _ = io.ReadAll(io.LimitReader(ir.gentlemanResponse.RawResponse.Body, limit+1))
+		ir.abort()
+	}
+	if int64(len(content)) > limit {
+		content = content[:limit]
+	}
+	return content
+}
+
+// Reader returns the response body. Unless it was already read in memory, the body is streamed from the connection and can only be read once
+func (ir *internalResponse) Reader() io.Reader {
+	if ir.bytes != nil || ir.loaded || ir.gentlemanResponse.RawResponse.Body == nil {
+		return bytes.NewReader(ir.Bytes())
+	}
+	return ir.gentlemanResponse.RawResponse.Body
 }
 
 func (ir *internalResponse) JSON(data any) error {
@@ -41,3 +108,26 @@ func (ir *internalResponse) JSON(data any) error {
 func (ir *internalResponse) String() string {
 	return string(ir.Bytes())
 }
+
+// Close discards at most maxDrainSize bytes of the remaining body, to let the connection be reused, then closes the body
+func (ir *internalResponse) Close() error {
+	var body = ir.gentlemanResponse.RawResponse.Body
+	if body == nil {
+		return nil
+	}
+	io.CopyN(io.Discard, body, maxDrainSize)
+	return body.Close()
+}
+
+// abort closes the response body without reading its remaining content
+func (ir *internalResponse) abort() {
+	if body := ir.gentlemanResponse.RawResponse.Body; body != nil {
+		body.Close()
+	}
+}
+
+// load reads the whole response body in memory
+func (ir *internalResponse) load() error {
+	var _, err = ir.read()
+	return err
+}
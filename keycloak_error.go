@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"fmt"
+)
+
+// KeycloakError is returned when the server answered with an OAuth2 error ({"error", "error_description"})
+// or with a Keycloak validation error ({"errorMessage", "field", "params"})
+type KeycloakError struct {
+	HTTPError
+	// ErrorCode is the OAuth2 error code, such as "invalid_grant"
+	ErrorCode string
+	// Description is the OAuth2 error description
+	Description string
+	// Field is the name of the field which failed validation
+	Field string
+	// Params are the parameters of the Keycloak error message
+	Params []string
+}
+
+// Unwrap gives access to the HTTPError
+func (e KeycloakError) Unwrap() error {
+	return e.HTTPError
+}
+
+// parseKeycloakError builds a KeycloakError from a JSON error payload. Payloads only made of an errorMessage are left to HTTPError
+func parseKeycloakError(statusCode int, response map[string]any) (KeycloakError, bool) {
+	var kcErr = KeycloakError{
+		HTTPError: HTTPError{StatusCode: statusCode},
+	}
+	kcErr.ErrorCode, _ = response["error"].(string)
+	kcErr.Description, _ = response["error_description"].(string)
+	kcErr.Field, _ = response["field"].(string)
+	if params, ok := response["params"].([]any); ok {
+		kcErr.Params = make([]string, 0, len(params))
+		for _, param := range params {
+			kcErr.Params = append(kcErr.Params, fmt.Sprint(param))
+		}
+	}
+	if kcErr.ErrorCode == "" && kcErr.Field == "" && kcErr.Params == nil {
+		return KeycloakError{}, false
+	}
+
+	var message, _ = response["errorMessage"].(string)
+	for _, candidate := range []string{message, kcErr.Description, kcErr.ErrorCode} {
+		if candidate != "" {
+			kcErr.Message = candidate
+			break
+		}
+	}
+	return kcErr, true
+}
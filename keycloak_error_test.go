@@ -0,0 +1,58 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeycloakError(t *testing.T) {
+	var client, _ = New("http://my.url", time.Minute)
+
+	t.Run("OAuth2 error", func(t *testing.T) {
+		var resp = createErrorResponse(http.StatusBadRequest, "application/json", `{"error": "invalid_grant", "error_description": "Invalid user credentials"}`)
+		var err = client.checkError(resp)
+		assert.Equal(t, KeycloakError{
+			HTTPError:   HTTPError{StatusCode: http.StatusBadRequest, Message: "Invalid user credentials"},
+			ErrorCode:   "invalid_grant",
+			Description: "Invalid user credentials",
+		}, err)
+	})
+	t.Run("OAuth2 error without description", func(t *testing.T) {
+		var resp = createErrorResponse(http.StatusUnauthorized, "application/json", `{"error": "invalid_client"}`)
+		var err = client.checkError(resp)
+
+		var kcErr KeycloakError
+		assert.True(t, errors.As(err, &kcErr))
+		assert.Equal(t, "invalid_client", kcErr.ErrorCode)
+		assert.Equal(t, "invalid_client", kcErr.Message)
+	})
+	t.Run("Keycloak validation error", func(t *testing.T) {
+		var resp = createErrorResponse(http.StatusBadRequest, "application/json", `{"errorMessage": "error-invalid-length", "field": "firstName", "params": ["firstName", 1, 255]}`)
+		var err = client.checkError(resp)
+
+		var kcErr KeycloakError
+		assert.True(t, errors.As(err, &kcErr))
+		assert.Equal(t, "firstName", kcErr.Field)
+		assert.Equal(t, []string{"firstName", "1", "255"}, kcErr.Params)
+		assert.Equal(t, "error-invalid-length", kcErr.Message)
+		assert.True(t, kcErr.IsErrorFromClient())
+
+		var httpErr HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, "400:error-invalid-length", httpErr.Error())
+	})
+	t.Run("Error message only", func(t *testing.T) {
+		var resp = createErrorResponse(http.StatusConflict, "application/json", `{"errorMessage": "User exists with same username"}`)
+		var err = client.checkError(resp)
+		assert.Equal(t, HTTPError{StatusCode: http.StatusConflict, Message: "User exists with same username"}, err)
+	})
+	t.Run("Error message is not a string", func(t *testing.T) {
+		var resp = createErrorResponse(http.StatusConflict, "application/json", `{"errorMessage": 12}`)
+		var err = client.checkError(resp)
+		assert.Equal(t, HTTPError{StatusCode: http.StatusConflict, Message: `{"errorMessage": 12}`}, err)
+	})
+}
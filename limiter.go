@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithRateLimit limits the rate of requests sent by the client to requestsPerSecond, allowing bursts of up to burst requests.
+// Each attempt of a retried request consumes a token. A non positive rate disables the limit
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(c *Client) {
+		c.rateLimiter = nil
+		if requestsPerSecond > 0 {
+			c.rateLimiter = newRateLimiter(requestsPerSecond, burst)
+		}
+	}
+}
+
+// WithMaxConcurrency limits the number of requests the client processes concurrently. A non positive value disables the limit
+func WithMaxConcurrency(maxConcurrentRequests int) Option {
+	return func(c *Client) {
+		c.bulkhead = nil
+		if maxConcurrentRequests > 0 {
+			c.bulkhead = make(bulkhead, maxConcurrentRequests)
+		}
+	}
+}
+
+// rateLimiter is a token bucket
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(requestsPerSecond float64, burst int) *rateLimiter {
+	var limiter = &rateLimiter{
+		rate:  requestsPerSecond,
+		burst: float64(max(burst, 1)),
+		now:   time.Now,
+	}
+	limiter.tokens = limiter.burst
+	limiter.last = limiter.now()
+	return limiter
+}
+
+// reserve takes a token from the bucket and returns how long to wait before using it
+func (l *rateLimiter) reserve() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var now = l.now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.burst)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel gives back a token which was reserved but not used
+func (l *rateLimiter) cancel() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.tokens = min(l.tokens+1, l.burst)
+}
+
+// wait blocks until a request can be sent. It fails immediately if ctx expires before a token is available
+func (l *rateLimiter) wait(ctx context.Context) error {
+	var delay = l.reserve()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		l.cancel()
+		return context.DeadlineExceeded
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		l.cancel()
+		return err
+	}
+	return nil
+}
+
+// bulkhead limits the number of concurrent requests
+type bulkhead chan struct{}
+
+func (b bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b bulkhead) release() {
+	<-b
+}
@@ -0,0 +1,120 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var now = time.Now()
+	var limiter = newRateLimiter(10, 2)
+	limiter.now = func() time.Time { return now }
+	limiter.last = now
+
+	t.Run("Burst", func(t *testing.T) {
+		assert.Equal(t, time.Duration(0), limiter.reserve())
+		assert.Equal(t, time.Duration(0), limiter.reserve())
+		assert.Equal(t, 100*time.Millisecond, limiter.reserve())
+		limiter.cancel()
+	})
+	t.Run("Refill", func(t *testing.T) {
+		now = now.Add(200 * time.Millisecond)
+		assert.Equal(t, time.Duration(0), limiter.reserve())
+		assert.Equal(t, time.Duration(0), limiter.reserve())
+		limiter.cancel()
+		limiter.cancel()
+	})
+	t.Run("Deadline too close", func(t *testing.T) {
+		limiter.reserve()
+		limiter.reserve()
+		var ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, limiter.wait(ctx))
+	})
+	t.Run("Wait for a token", func(t *testing.T) {
+		var start = time.Now()
+		assert.Nil(t, limiter.wait(context.Background()))
+		assert.True(t, time.Since(start) >= 100*time.Millisecond)
+	})
+}
+
+func TestClientRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRateLimit(20, 1))
+
+	var start = time.Now()
+	for range 3 {
+		assert.Nil(t, client.Delete(url.Path("/sample")))
+	}
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	var err = client.DeleteCtx(ctx, url.Path("/sample"))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClientMaxConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	var unblock = make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var current = atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			var previous = atomic.LoadInt32(&maxInFlight)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxInFlight, previous, current) {
+				break
+			}
+		}
+		<-unblock
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithMaxConcurrency(2))
+
+	t.Run("Waiting request is cancelled", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Nil(t, client.Delete(url.Path("/sample")))
+			}()
+		}
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&inFlight) == 2 }, time.Second, time.Millisecond)
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		var err = client.DeleteCtx(ctx, url.Path("/sample"))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+		close(unblock)
+		wg.Wait()
+	})
+	t.Run("Concurrency is bounded", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Nil(t, client.Delete(url.Path("/sample")))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+	})
+}
@@ -0,0 +1,67 @@
+package httpclient
+
+import (
+	"encoding/json"
+)
+
+// MediaTypeProblemJSON is the media type of the RFC 9457 problem details
+const MediaTypeProblemJSON = "application/problem+json"
+
+// ProblemError is returned when the server answered with RFC 9457 problem details.
+// The HTTP status code of the response is reported by StatusCode, Message contains the problem detail, or its title when there is no detail
+type ProblemError struct {
+	HTTPError
+	// Type is the URI reference identifying the problem type. It defaults to "about:blank"
+	Type string
+	// Title is a short summary of the problem type
+	Title string
+	// Detail is an explanation specific to this occurrence of the problem
+	Detail string
+	// Instance is the URI reference identifying this occurrence of the problem
+	Instance string
+	// Extensions contains the members of the problem details which are not defined by RFC 9457
+	Extensions map[string]any
+}
+
+// Unwrap gives access to the HTTPError
+func (e ProblemError) Unwrap() error {
+	return e.HTTPError
+}
+
+// parseProblem parses the problem details contained in the body of a response with the given status code
+func parseProblem(statusCode int, body []byte) (ProblemError, bool) {
+	var members map[string]any
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return ProblemError{}, false
+	}
+
+	var problem = ProblemError{
+		HTTPError: HTTPError{StatusCode: statusCode},
+		Type:      "about:blank",
+	}
+	for name, value := range members {
+		var text, isString = value.(string)
+		switch {
+		case name == "type" && isString:
+			problem.Type = text
+		case name == "title" && isString:
+			problem.Title = text
+		case name == "detail" && isString:
+			problem.Detail = text
+		case name == "instance" && isString:
+			problem.Instance = text
+		case name == "status":
+			// The status code of the HTTP response prevails
+		default:
+			if problem.Extensions == nil {
+				problem.Extensions = map[string]any{}
+			}
+			problem.Extensions[name] = value
+		}
+	}
+	problem.Message = problem.Detail
+	if problem.Message == "" {
+		problem.Message = problem.Title
+	}
+	return problem, true
+}
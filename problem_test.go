@@ -0,0 +1,114 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+func createErrorResponse(status int, mimeType string, text string) *internalResponse {
+	var resp = createResponse(mimeType, text)
+	resp.gentlemanResponse.StatusCode = status
+	return resp
+}
+
+func TestParseProblem(t *testing.T) {
+	t.Run("Problem details with extensions", func(t *testing.T) {
+		var problem, ok = parseProblem(http.StatusForbidden, []byte(`{
+			"type": "https://example.com/probs/out-of-credit",
+			"title": "You do not have enough credit.",
+			"status": 403,
+			"detail": "Your current balance is 30, but that costs 50.",
+			"instance": "/account/12345/msgs/abc",
+			"balance": 30
+		}`))
+		assert.True(t, ok)
+		assert.Equal(t, ProblemError{
+			HTTPError:  HTTPError{StatusCode: http.StatusForbidden, Message: "Your current balance is 30, but that costs 50."},
+			Type:       "https://example.com/probs/out-of-credit",
+			Title:      "You do not have enough credit.",
+			Detail:     "Your current balance is 30, but that costs 50.",
+			Instance:   "/account/12345/msgs/abc",
+			Extensions: map[string]any{"balance": float64(30)},
+		}, problem)
+		assert.True(t, problem.IsErrorFromClient())
+		assert.Equal(t, "403:Your current balance is 30, but that costs 50.", problem.Error())
+	})
+	t.Run("Minimal problem details", func(t *testing.T) {
+		var problem, ok = parseProblem(http.StatusNotFound, []byte(`{"title": "Not Found"}`))
+		assert.True(t, ok)
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, "Not Found", problem.Message)
+		assert.Nil(t, problem.Extensions)
+	})
+	t.Run("Invalid problem details", func(t *testing.T) {
+		var _, ok = parseProblem(http.StatusNotFound, []byte(`not json`))
+		assert.False(t, ok)
+	})
+}
+
+func TestProblemError(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(testRetryPolicy()))
+
+	t.Run("Client error", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"title": "Unauthorized", "detail": "Token expired"}`))
+		})
+		var err = client.Get(nil, url.Path(path))
+
+		var problem ProblemError
+		assert.True(t, errors.As(err, &problem))
+		assert.Equal(t, "Token expired", problem.Detail)
+		assert.Equal(t, 1, problem.Attempts)
+
+		var httpErr HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.True(t, httpErr.IsErrorFromClient())
+		assert.Equal(t, http.StatusUnauthorized, httpErr.Status())
+	})
+	t.Run("Retried server error", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"title": "Maintenance"}`))
+		}).Times(3)
+		var err = client.Get(nil, url.Path(path))
+
+		var problem ProblemError
+		assert.True(t, errors.As(err, &problem))
+		assert.Equal(t, "Maintenance", problem.Title)
+		assert.Equal(t, 3, problem.Attempts)
+		assert.True(t, problem.IsErrorFromServer())
+	})
+	t.Run("Invalid problem details fall back to HTTPError", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`oops`))
+		})
+		var err = client.Get(nil, url.Path(path))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusBadRequest, Message: "oops", Attempts: 1}, withoutRequestDetails(err))
+	})
+}
@@ -0,0 +1,118 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// ProgressFunc receives the number of bytes transferred so far and the total number of bytes to transfer, which is -1 when unknown
+type ProgressFunc func(transferred, total int64)
+
+// UploadProgress reports the progress of the transfer of the request body to callback, at most once per interval and once the body is fully sent.
+// The total is the Content-Length of the request. The callback is called from the goroutine sending the request body.
+// The progress restarts from zero when the request is retried
+func UploadProgress(callback ProgressFunc, interval time.Duration) plugin.Plugin {
+	return plugin.NewPhasePlugin("before dial", func(ctx *context.Context, h context.Handler) {
+		var req = ctx.Request
+		if req.Body != nil && req.Body != http.NoBody {
+			var total = req.ContentLength
+			if total <= 0 {
+				total = -1
+			}
+			req.Body = &progressReader{
+				body:     req.Body,
+				reporter: newProgressReporter(callback, interval, 0, total),
+			}
+		}
+		h.Next(ctx)
+	})
+}
+
+// DownloadProgress reports the progress of the transfer of the response body to callback, at most once per interval and once the body is fully read.
+// The total is the Content-Length of the response. When a download is resumed, the progress starts from the position given by the Content-Range header.
+// The callback is called from the goroutine reading the response body
+func DownloadProgress(callback ProgressFunc, interval time.Duration) plugin.Plugin {
+	return plugin.NewResponsePlugin(func(ctx *context.Context, h context.Handler) {
+		var resp = ctx.Response
+		if resp != nil && resp.Body != nil && resp.Body != http.NoBody {
+			var start, total = int64(0), resp.ContentLength
+			if resp.StatusCode == http.StatusPartialContent {
+				if rangeStart, rangeTotal, ok := parseContentRange(resp.Header.Get("Content-Range")); ok {
+					start, total = rangeStart, rangeTotal
+				}
+			}
+			if total < 0 {
+				total = -1
+			}
+			resp.Body = &progressReader{
+				body:     resp.Body,
+				reporter: newProgressReporter(callback, interval, start, total),
+			}
+		}
+		h.Next(ctx)
+	})
+}
+
+// progressReporter calls a ProgressFunc at most once per interval
+type progressReporter struct {
+	callback ProgressFunc
+	interval time.Duration
+	now      func() time.Time
+
+	mutex       sync.Mutex
+	transferred int64
+	total       int64
+	last        time.Time
+	done        bool
+}
+
+func newProgressReporter(callback ProgressFunc, interval time.Duration, start, total int64) *progressReporter {
+	return &progressReporter{
+		callback:    callback,
+		interval:    interval,
+		now:         time.Now,
+		transferred: start,
+		total:       total,
+	}
+}
+
+// add records that n more bytes were transferred. The callback is called when the interval elapsed since the last report or when the transfer is complete
+func (r *progressReporter) add(n int64, complete bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.done {
+		return
+	}
+	r.transferred += n
+	complete = complete || (r.total >= 0 && r.transferred >= r.total)
+	var now = r.now()
+	if complete || now.Sub(r.last) >= r.interval {
+		r.last = now
+		r.done = complete
+		r.callback(r.transferred, r.total)
+	}
+}
+
+// progressReader reports the bytes read from body
+type progressReader struct {
+	body     io.ReadCloser
+	reporter *progressReporter
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	var n, err = p.body.Read(b)
+	if n > 0 || err == io.EOF {
+		p.reporter.add(int64(n), err == io.EOF)
+	}
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	return p.body.Close()
+}
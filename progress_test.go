@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+type progressRecorder struct {
+	mutex   sync.Mutex
+	reports [][2]int64
+}
+
+func (r *progressRecorder) record(transferred, total int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reports = append(r.reports, [2]int64{transferred, total})
+}
+
+func (r *progressRecorder) last() [2]int64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.reports[len(r.reports)-1]
+}
+
+func TestProgress(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/realm"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(testRetryPolicy()))
+	var content = strings.Repeat("0123456789", 100000)
+	var size = int64(len(content))
+
+	var consumeBody = func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	t.Run("Upload with known length", func(t *testing.T) {
+		var recorder progressRecorder
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(consumeBody)
+		var err = client.Put(url.Path(path), BodyReader(strings.NewReader(content), size), UploadProgress(recorder.record, 0))
+		assert.Nil(t, err)
+		assert.True(t, len(recorder.reports) > 1)
+		assert.Equal(t, [2]int64{size, size}, recorder.last())
+	})
+	t.Run("Chunked upload", func(t *testing.T) {
+		var recorder progressRecorder
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(consumeBody)
+		var err = client.Put(url.Path(path), UploadProgress(recorder.record, time.Hour), BodyReader(io.MultiReader(strings.NewReader(content)), -1))
+		assert.Nil(t, err)
+		assert.Len(t, recorder.reports, 2)
+		assert.Equal(t, [2]int64{size, -1}, recorder.last())
+	})
+	t.Run("Download", func(t *testing.T) {
+		var recorder progressRecorder
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write([]byte(content))
+		})
+		var buffer bytes.Buffer
+		var _, err = client.Download(&buffer, url.Path(path), DownloadProgress(recorder.record, 0))
+		assert.Nil(t, err)
+		assert.True(t, len(recorder.reports) > 1)
+		for i := 1; i < len(recorder.reports); i++ {
+			assert.True(t, recorder.reports[i][0] > recorder.reports[i-1][0])
+		}
+		assert.Equal(t, [2]int64{size, size}, recorder.last())
+	})
+	t.Run("Resumed download", func(t *testing.T) {
+		var recorder progressRecorder
+		var modTime = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				w.Write([]byte(content[:len(content)/2]))
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "", modTime, strings.NewReader(content))
+			}),
+		)
+		var _, err = client.Download(io.Discard, url.Path(path), DownloadProgress(recorder.record, 0))
+		assert.Nil(t, err)
+		assert.Equal(t, [2]int64{size, size}, recorder.last())
+	})
+}
+
+func TestProgressReporter(t *testing.T) {
+	var now = time.Now()
+	var recorder progressRecorder
+	var reporter = newProgressReporter(recorder.record, time.Second, 0, 100)
+	reporter.now = func() time.Time { return now }
+
+	reporter.add(10, false)
+	reporter.add(10, false)
+	now = now.Add(time.Second)
+	reporter.add(10, false)
+	reporter.add(70, false)
+	reporter.add(0, true)
+	assert.Equal(t, [][2]int64{{10, 100}, {30, 100}, {100, 100}}, recorder.reports)
+}
@@ -0,0 +1,306 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/headers"
+)
+
+// bodyPlugin sets the request body to a stream opened each time the plugin is applied to a request.
+// Requests whose body can not be opened twice are never retried
+type bodyPlugin struct {
+	plugin.Plugin
+	replayable bool
+}
+
+// newBodyPlugin creates a plugin setting the request body to the stream returned by open, having the given content type and size.
+// A negative size sends the body with chunked transfer encoding
+func newBodyPlugin(contentType string, replayable bool, open func() (io.ReadCloser, int64, error)) plugin.Plugin {
+	return &bodyPlugin{
+		Plugin: plugin.NewRequestPlugin(func(ctx *context.Context, h context.Handler) {
+			var body, size, err = open()
+			if err != nil {
+				h.Error(ctx, err)
+				return
+			}
+			ctx.Request.Body = body
+			ctx.Request.ContentLength = size
+			ctx.Request.GetBody = nil
+			if size == 0 {
+				body.Close()
+				ctx.Request.Body = http.NoBody
+			}
+			if contentType != "" {
+				ctx.Request.Header.Set("Content-Type", contentType)
+			}
+			h.Next(ctx)
+		}),
+		replayable: replayable,
+	}
+}
+
+// replayable is false when one of the plugins sets a request body which can only be sent once
+func replayable(plugins []plugin.Plugin) bool {
+	for _, p := range plugins {
+		if body, ok := p.(*bodyPlugin); ok && !body.replayable {
+			return false
+		}
+	}
+	return true
+}
+
+// hasBodyPlugin is true when one of the plugins sets the request body using newBodyPlugin
+func hasBodyPlugin(plugins []plugin.Plugin) bool {
+	for _, p := range plugins {
+		if _, ok := p.(*bodyPlugin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyReplay keeps in memory the request body sent by the first attempt of a request, to send it again when the request is retried.
+// It is used for the bodies set by other plugins than the ones of this package, whose content can only be read once
+type bodyReplay struct {
+	content []byte
+	loaded  bool
+}
+
+// plugin returns a plugin replacing the request body with the content kept in memory. It must be applied before the plugins
+// wrapping the request body, such as UploadProgress
+func (r *bodyReplay) plugin() plugin.Plugin {
+	return plugin.NewPhasePlugin("before dial", func(ctx *context.Context, h context.Handler) {
+		var req = ctx.Request
+		if req.Body == nil || req.Body == http.NoBody {
+			h.Next(ctx)
+			return
+		}
+		if !r.loaded {
+			var content, err = io.ReadAll(req.Body)
+			if err != nil {
+				req.Body.Close()
+				h.Error(ctx, err)
+				return
+			}
+			r.content, r.loaded = content, true
+		}
+		req.Body.Close()
+		req.Body, req.ContentLength = http.NoBody, 0
+		if len(r.content) > 0 {
+			req.Body = io.NopCloser(bytes.NewReader(r.content))
+			req.ContentLength = int64(len(r.content))
+		}
+		req.GetBody = nil
+		h.Next(ctx)
+	})
+}
+
+// BodyReader sets the request body to content, which is read while the request is sent. size is the length of content,
+// or -1 when it is unknown in which case the body is sent with chunked transfer encoding.
+// A request with a body which is not an io.Seeker can not be retried, a seekable body is rewound before each attempt
+func BodyReader(content io.Reader, size int64) plugin.Plugin {
+	var seeker, replay = content.(io.Seeker)
+	var start int64
+	if replay {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			replay = false
+		}
+	}
+	return newBodyPlugin("", replay, func() (io.ReadCloser, int64, error) {
+		if replay {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, 0, err
+			}
+		}
+		return io.NopCloser(content), max(size, -1), nil
+	})
+}
+
+// contentTypePlugin sets the Content-Type of the request. It selects the encoder of the bodies sent with PostBody, PutBody and PatchBody
+type contentTypePlugin struct {
+	plugin.Plugin
+	mediaType string
+}
+
+// ContentType sets the Content-Type header of the request. The request bodies sent with PostBody, PutBody and PatchBody are encoded
+// by the encoder registered for this media type, JSON being used when the request has no ContentType plugin
+func ContentType(mediaType string) plugin.Plugin {
+	return &contentTypePlugin{
+		Plugin:    headers.Set("Content-Type", mediaType),
+		mediaType: mediaType,
+	}
+}
+
+// encodeBody returns a plugin setting the request body to data, encoded according to the media type given by the ContentType plugins.
+// The body is encoded in memory once and sent again when the request is retried
+func (c *Client) encodeBody(data any, plugins []plugin.Plugin) (plugin.Plugin, error) {
+	var mediaType = MediaTypeJSON
+	for _, p := range plugins {
+		if contentType, ok := p.(*contentTypePlugin); ok {
+			mediaType = contentType.mediaType
+		}
+	}
+	var encoder, ok = c.encoders.lookup(mediaType)
+	if !ok {
+		return nil, fmt.Errorf("%s.%v", MsgErrUnkownHTTPContentType, mediaType)
+	}
+	var buffer bytes.Buffer
+	if err := encoder(&buffer, data); err != nil {
+		return nil, err
+	}
+	var content = buffer.Bytes()
+	return newBodyPlugin(mediaType, true, func() (io.ReadCloser, int64, error) {
+		return io.NopCloser(bytes.NewReader(content)), int64(len(content)), nil
+	}), nil
+}
+
+// MultipartForm builds a multipart/form-data request body. Files are streamed while the request is sent, without being loaded in memory
+type MultipartForm struct {
+	boundary string
+	parts    []multipartPart
+}
+
+type multipartPart struct {
+	header  textproto.MIMEHeader
+	content io.Reader
+}
+
+// NewMultipartForm creates an empty multipart form
+func NewMultipartForm() *MultipartForm {
+	return &MultipartForm{
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}
+}
+
+// Field adds a form field
+func (f *MultipartForm) Field(name, value string) *MultipartForm {
+	var header = textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(name)))
+	f.parts = append(f.parts, multipartPart{header: header, content: strings.NewReader(value)})
+	return f
+}
+
+// File adds a file part whose content is read from content while the request is sent. An empty contentType defaults to application/octet-stream
+func (f *MultipartForm) File(fieldName, fileName, contentType string, content io.Reader) *MultipartForm {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	var header = textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(fieldName), escapeQuotes(fileName)))
+	header.Set("Content-Type", contentType)
+	f.parts = append(f.parts, multipartPart{header: header, content: content})
+	return f
+}
+
+// ContentType returns the multipart/form-data media type of the form, including its boundary
+func (f *MultipartForm) ContentType() string {
+	return "multipart/form-data; boundary=" + f.boundary
+}
+
+// Body returns a plugin setting the request body to the form, sent with chunked transfer encoding.
+// The form can only be sent once, unless the contents of all its parts are io.Seeker which are rewound before each attempt
+func (f *MultipartForm) Body() plugin.Plugin {
+	var starts = make([]int64, len(f.parts))
+	var replay = true
+	for i, part := range f.parts {
+		var seeker, ok = part.content.(io.Seeker)
+		if !ok {
+			replay = false
+			break
+		}
+		var err error
+		if starts[i], err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			replay = false
+			break
+		}
+	}
+	var previous *multipartBody
+	return newBodyPlugin(f.ContentType(), replay, func() (io.ReadCloser, int64, error) {
+		if replay {
+			// The body of the previous attempt must stop reading the parts before they are rewound
+			if previous != nil {
+				previous.Close()
+			}
+			for i, part := range f.parts {
+				if _, err := part.content.(io.Seeker).Seek(starts[i], io.SeekStart); err != nil {
+					return nil, 0, err
+				}
+			}
+		}
+		previous = &multipartBody{form: f}
+		return previous, -1, nil
+	})
+}
+
+// write writes the form to w
+func (f *MultipartForm) write(w io.Writer) error {
+	var writer = multipart.NewWriter(w)
+	if err := writer.SetBoundary(f.boundary); err != nil {
+		return err
+	}
+	for _, part := range f.parts {
+		var partWriter, err = writer.CreatePart(part.header)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(partWriter, part.content); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// multipartBody streams a multipart form through a pipe. The form is written by a goroutine started on the first read
+type multipartBody struct {
+	form   *MultipartForm
+	once   sync.Once
+	reader *io.PipeReader
+	done   chan struct{}
+}
+
+func (b *multipartBody) start() {
+	b.once.Do(func() {
+		var writer *io.PipeWriter
+		b.reader, writer = io.Pipe()
+		b.done = make(chan struct{})
+		go func() {
+			defer close(b.done)
+			writer.CloseWithError(b.form.write(writer))
+		}()
+	})
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.start()
+	if b.reader == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return b.reader.Read(p)
+}
+
+// Close stops the goroutine writing the form and waits for it to return. A body closed before being read is never written
+func (b *multipartBody) Close() error {
+	b.once.Do(func() {})
+	if b.reader == nil {
+		return nil
+	}
+	var err = b.reader.Close()
+	<-b.done
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,256 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/headers"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+func TestBodyReader(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/upload"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(testRetryPolicy()))
+	var content = strings.Repeat("0123456789", 1000)
+
+	var expectBody = func(status int) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var body, _ = io.ReadAll(r.Body)
+			assert.Equal(t, content, string(body))
+			w.WriteHeader(status)
+		}
+	}
+
+	t.Run("Known length", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, int64(len(content)), r.ContentLength)
+			assert.Empty(t, r.TransferEncoding)
+			assert.Equal(t, "text/csv", r.Header.Get("Content-Type"))
+			expectBody(http.StatusCreated)(w, r)
+		})
+		var _, err = client.Post(nil, url.Path(path), BodyReader(strings.NewReader(content), int64(len(content))), headers.Set("Content-Type", "text/csv"))
+		assert.Nil(t, err)
+	})
+	t.Run("Chunked", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, []string{"chunked"}, r.TransferEncoding)
+			expectBody(http.StatusNoContent)(w, r)
+		})
+		var err = client.Put(url.Path(path), BodyReader(io.MultiReader(strings.NewReader(content)), -1))
+		assert.Nil(t, err)
+	})
+	t.Run("Seekable body is sent again when retried", func(t *testing.T) {
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.StatusServiceUnavailable)),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.StatusNoContent)),
+		)
+		var err = client.Put(url.Path(path), BodyReader(strings.NewReader(content), int64(len(content))))
+		assert.Nil(t, err)
+	})
+	t.Run("Stream is not retried", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.StatusServiceUnavailable))
+		var err = client.Put(url.Path(path), BodyReader(io.MultiReader(strings.NewReader(content)), -1))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusServiceUnavailable, Attempts: 1}, withoutRequestDetails(err))
+	})
+	t.Run("Empty body", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, int64(0), r.ContentLength)
+			w.WriteHeader(http.StatusNoContent)
+		})
+		var err = client.Put(url.Path(path), BodyReader(strings.NewReader(""), 0))
+		assert.Nil(t, err)
+	})
+}
+
+func TestEncodedBody(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/users"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(testRetryPolicy()))
+
+	var expectBody = func(method, contentType, content string, status int) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var body, _ = io.ReadAll(r.Body)
+			assert.Equal(t, method, r.Method)
+			assert.Equal(t, contentType, r.Header.Get("Content-Type"))
+			if content != "" {
+				assert.Equal(t, int64(len(content)), r.ContentLength)
+			}
+			assert.Equal(t, content, string(body))
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Location", "/users/1")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"key1": "created", "key2": 1}`))
+		}
+	}
+
+	t.Run("JSON by default", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.MethodPost, "application/json", `{"key1":"value","key2":2}`, http.StatusCreated))
+		var out sampleEntity
+		var location, err = client.PostBody(sampleEntity{Key1: "value", Key2: 2}, &out, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, "/users/1", location)
+		assert.Equal(t, sampleEntity{Key1: "created", Key2: 1}, out)
+	})
+	t.Run("Form", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.MethodPut, MediaTypeFormURLEncoded, "key=value", http.StatusOK))
+		var _, err = client.PutBody(map[string]string{"key": "value"}, nil, url.Path(path), ContentType(MediaTypeFormURLEncoded))
+		assert.Nil(t, err)
+	})
+	t.Run("XML", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.MethodPatch, "application/xml", "<sampleEntity><Key1>value</Key1><Key2>2</Key2></sampleEntity>", http.StatusOK))
+		var err = client.PatchBody(sampleEntity{Key1: "value", Key2: 2}, nil, url.Path(path), ContentType("application/xml"))
+		assert.Nil(t, err)
+	})
+	t.Run("Raw body is sent again when retried", func(t *testing.T) {
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.MethodPut, "text/plain", "content", http.StatusServiceUnavailable)),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.MethodPut, "text/plain", "content", http.StatusOK)),
+		)
+		var _, err = client.PutBodyCtx(context.Background(), "content", nil, url.Path(path), ContentType("text/plain"))
+		assert.Nil(t, err)
+	})
+	t.Run("No body", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.MethodPost, "", "", http.StatusOK))
+		var _, err = client.PostBodyCtx(context.Background(), nil, nil, url.Path(path))
+		assert.Nil(t, err)
+	})
+	t.Run("Unknown media type", func(t *testing.T) {
+		var _, err = client.PostBody("content", nil, url.Path(path), ContentType("text/html"))
+		assert.Contains(t, err.Error(), MsgErrUnkownHTTPContentType)
+	})
+	t.Run("Data can't be encoded", func(t *testing.T) {
+		var err = client.PatchBodyCtx(context.Background(), 12, nil, url.Path(path), ContentType("application/octet-stream"))
+		assert.Contains(t, err.Error(), MsgErrUnsupportedDataType)
+	})
+}
+
+func TestMultipartForm(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/upload"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(testRetryPolicy()))
+	var export = bytes.Repeat([]byte(`{"realm": "master"}`), 100000)
+
+	var expectForm = func(status int) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, []string{"chunked"}, r.TransferEncoding)
+			assert.Nil(t, r.ParseMultipartForm(1<<20))
+			assert.Equal(t, "true", r.FormValue("overwrite"))
+			assert.Equal(t, `a "quoted" value`, r.FormValue(`field "name"`))
+
+			var file, header, err = r.FormFile("file")
+			assert.Nil(t, err)
+			assert.Equal(t, "realm-export.json", header.Filename)
+			assert.Equal(t, "application/json", header.Header.Get("Content-Type"))
+			var content, _ = io.ReadAll(file)
+			assert.Equal(t, export, content)
+
+			var _, other, _ = r.FormFile("other")
+			assert.Equal(t, "application/octet-stream", other.Header.Get("Content-Type"))
+			w.WriteHeader(status)
+		}
+	}
+
+	t.Run("Streamed files", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectForm(http.StatusServiceUnavailable))
+		var form = NewMultipartForm().
+			Field("overwrite", "true").
+			Field(`field "name"`, `a "quoted" value`).
+			File("file", "realm-export.json", "application/json", io.MultiReader(bytes.NewReader(export))).
+			File("other", "other.bin", "", strings.NewReader("other"))
+		var err = client.Put(url.Path(path), form.Body())
+		assert.Equal(t, HTTPError{StatusCode: http.StatusServiceUnavailable, Attempts: 1}, withoutRequestDetails(err))
+	})
+	t.Run("Seekable files are sent again when retried", func(t *testing.T) {
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectForm(http.StatusServiceUnavailable)),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectForm(http.StatusNoContent)),
+		)
+		var form = NewMultipartForm().
+			Field("overwrite", "true").
+			Field(`field "name"`, `a "quoted" value`).
+			File("file", "realm-export.json", "application/json", bytes.NewReader(export)).
+			File("other", "other.bin", "", strings.NewReader("other"))
+		var err = client.Put(url.Path(path), form.Body())
+		assert.Nil(t, err)
+	})
+	t.Run("Content type", func(t *testing.T) {
+		var form = NewMultipartForm()
+		assert.True(t, strings.HasPrefix(form.ContentType(), "multipart/form-data; boundary="))
+	})
+	t.Run("Close waits for the form to stop being written", func(t *testing.T) {
+		var release = make(chan struct{})
+		var body = &multipartBody{form: NewMultipartForm().File("file", "export.json", "", blockingReader(release))}
+		// Reads the header of the part, the form being then written until the content of the file is read
+		var _, err = body.Read(make([]byte, 1024))
+		assert.Nil(t, err)
+
+		var closed = make(chan struct{})
+		go func() {
+			body.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+			assert.Fail(t, "Close returned while the form was being written")
+		case <-time.After(20 * time.Millisecond):
+		}
+		close(release)
+		<-closed
+	})
+	t.Run("Body closed before being read", func(t *testing.T) {
+		var body = &multipartBody{form: NewMultipartForm().Field("name", "value")}
+		assert.Nil(t, body.Close())
+		var _, err = body.Read(make([]byte, 10))
+		assert.Equal(t, io.ErrClosedPipe, err)
+	})
+}
+
+// blockingReader is a reader blocking until its channel is closed
+type blockingReader chan struct{}
+
+func (r blockingReader) Read(p []byte) (int, error) {
+	<-r
+	return 0, io.EOF
+}
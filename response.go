@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// Response is a HTTP response
+type Response struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Header contains the response headers
+	Header http.Header
+	// Duration is the time elapsed between the first attempt and the reception of the response headers
+	Duration time.Duration
+	// Attempts is the number of requests sent to obtain the response
+	Attempts int
+
+	content *internalResponse
+	client  *Client
+}
+
+func buildResponse(client *Client, resp *internalResponse, duration time.Duration, attempts int) *Response {
+	return &Response{
+		StatusCode: resp.StatusCode(),
+		Header:     resp.Header(),
+		Duration:   duration,
+		Attempts:   attempts,
+		content:    resp,
+		client:     client,
+	}
+}
+
+// Decode decodes the response body into data using the decoder of the client registered for the response media type.
+// Unless it was already read in memory, the body is decoded while it is received
+func (r *Response) Decode(data any) error {
+	return r.client.readContent(r.content, data)
+}
+
+// Reader returns the response body. Unless the body was already read in memory, it is read from the connection and can only be read once
+func (r *Response) Reader() io.Reader {
+	return r.content.Reader()
+}
+
+// Bytes returns the raw response body. It returns nil when the body could not be read, the read error being returned by Stream
+func (r *Response) Bytes() []byte {
+	return r.content.Bytes()
+}
+
+// String returns the response body as a string
+func (r *Response) String() string {
+	return r.content.String()
+}
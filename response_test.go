@@ -0,0 +1,76 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+func TestDo(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var mockTokenProvider = mock.NewOidcTokenProvider(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(testRetryPolicy()))
+
+	t.Run("Success after a retry", func(t *testing.T) {
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			}),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v2"`)
+				w.Header().Set("Link", `</sample?page=2>; rel="next"`)
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte(`{"key1": "value1"}`))
+			}),
+		)
+		var resp, err = client.Do(context.Background(), http.MethodGet, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, `"v2"`, resp.Header.Get("ETag"))
+		assert.Equal(t, `</sample?page=2>; rel="next"`, resp.Header.Get("Link"))
+		assert.Equal(t, []byte(`{"key1": "value1"}`), resp.Bytes())
+		assert.Equal(t, `{"key1": "value1"}`, resp.String())
+		assert.Equal(t, 2, resp.Attempts)
+		assert.True(t, resp.Duration > 0)
+	})
+	t.Run("Error status", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`already exists`))
+		})
+		var resp, err = client.Do(context.Background(), http.MethodPost, url.Path(path))
+		assert.Nil(t, resp)
+		assert.Equal(t, HTTPError{StatusCode: http.StatusConflict, Message: "already exists", Attempts: 1}, withoutRequestDetails(err))
+	})
+	t.Run("MultiRealmTokenClient", func(t *testing.T) {
+		var realmClient, _ = NewMultiRealmTokenClient(ts.URL, time.Minute, mockTokenProvider)
+		mockTokenProvider.EXPECT().ProvideTokenForRealm(gomock.Any(), "my-realm").Return("token", nil)
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusNoContent)
+		})
+		var resp, err = realmClient.ForRealm("my-realm").Do(context.Background(), http.MethodDelete, url.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Nil(t, resp.Bytes())
+	})
+}
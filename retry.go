@@ -0,0 +1,169 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy defines how a Client retries failed requests.
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of requests sent, including the first one
+	MaxAttempts int
+	// InitialBackoff is the delay waited before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff is the upper bound of the delay waited between two attempts
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after each attempt
+	Multiplier float64
+	// Jitter is the proportion of the delay, between 0 and 1, which is randomized
+	Jitter float64
+	// Methods are the HTTP methods which can be retried. The request body of these methods is kept in memory to be sent again,
+	// unless it is set by BodyReader or MultipartForm
+	Methods []string
+	// RetryStatus tells whether a request answered with the given status code should be retried
+	RetryStatus func(statusCode int) bool
+	// RetryError tells whether a request which failed with the given network error should be retried
+	RetryError func(err error) bool
+	// MaxRetryAfter is the longest Retry-After delay the client accepts to wait. Zero means no limit
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryPolicy returns a retry policy which retries idempotent requests up to 3 times with exponential backoff
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		Methods:        []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete},
+		RetryStatus:    IsRetryableStatus,
+		RetryError:     IsRetryableError,
+		MaxRetryAfter:  time.Minute,
+	}
+}
+
+// WithRetryPolicy configures how the client retries failed requests
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// IsRetryableStatus is true for status codes reporting a transient failure or an overload of the upstream server
+func IsRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryableError is true for network errors which are likely to be transient (timeouts, refused or reset connections, connections
+// closed before the response was complete, temporary DNS failures). Permanent failures, such as an unknown host, are not retried
+func IsRetryableError(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (p RetryPolicy) maxAttempts(method string) int {
+	if p.MaxAttempts < 1 || !slices.Contains(p.Methods, method) {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) retryStatus(statusCode int) bool {
+	return p.RetryStatus != nil && p.RetryStatus(statusCode)
+}
+
+func (p RetryPolicy) retryError(err error) bool {
+	return p.RetryError != nil && p.RetryError(err)
+}
+
+// canWait is true when the server requested delay is acceptable and elapses before the deadline of ctx
+func (p RetryPolicy) canWait(ctx context.Context, delay time.Duration) bool {
+	if p.MaxRetryAfter > 0 && delay > p.MaxRetryAfter {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return false
+	}
+	return true
+}
+
+// backoff returns the delay to wait after the given attempt (starting from 1)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	var delay = float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		delay *= max(p.Multiplier, 1)
+		if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+			delay = float64(p.MaxBackoff)
+			break
+		}
+	}
+	if p.Jitter > 0 {
+		delay -= delay * min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// parseRetryAfter parses the value of a Retry-After header, expressed either in delta-seconds or as an HTTP-date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleepContext waits for the given delay unless ctx is done first
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	var timer = time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,237 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/httpclient/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/plugins/body"
+	urlplugin "gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+func testRetryPolicy() RetryPolicy {
+	var policy = DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func TestRetry(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockHandler = mock.NewHandler(mockCtrl)
+	var path = "/sample"
+
+	r := mux.NewRouter()
+	r.Handle(path, mockHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var client, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(testRetryPolicy()))
+
+	var respondWith = func(status int, body string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}
+	}
+
+	t.Run("Succeeds after transient failures", func(t *testing.T) {
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusBadGateway, "bad gateway")),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusServiceUnavailable, "unavailable")),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusOK, "response")),
+		)
+		var resp string
+		var err = client.Get(&resp, urlplugin.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, "response", resp)
+	})
+	t.Run("Gives up after max attempts", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusServiceUnavailable, "unavailable")).Times(3)
+		var err = client.Put(urlplugin.Path(path))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusServiceUnavailable, Message: "unavailable", Attempts: 3}, withoutRequestDetails(err))
+		assert.Equal(t, "PUT "+ts.URL+path+": 503:unavailable (after 3 attempts)", err.Error())
+	})
+	t.Run("Non retryable status", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusBadRequest, "bad request"))
+		var err = client.Delete(urlplugin.Path(path))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusBadRequest, Message: "bad request", Attempts: 1}, withoutRequestDetails(err))
+	})
+	t.Run("POST is not retried by default", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusBadGateway, "bad gateway"))
+		var _, err = client.Post(nil, urlplugin.Path(path))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusBadGateway, Message: "bad gateway", Attempts: 1}, withoutRequestDetails(err))
+	})
+	t.Run("Context cancelled during backoff", func(t *testing.T) {
+		var policy = testRetryPolicy()
+		policy.InitialBackoff = time.Hour
+		policy.MaxBackoff = time.Hour
+		var slowClient, _ = NewWithOptions(ts.URL, time.Minute, WithRetryPolicy(policy))
+
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(respondWith(http.StatusBadGateway, "bad gateway"))
+		var ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		var err = slowClient.GetCtx(ctx, nil, urlplugin.Path(path))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+	t.Run("Honours Retry-After", func(t *testing.T) {
+		var first time.Time
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				first = time.Now()
+				w.Header().Set("Retry-After", "1")
+				respondWith(http.StatusTooManyRequests, "slow down")(w, r)
+			}),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+				assert.True(t, time.Since(first) >= time.Second)
+				respondWith(http.StatusOK, "response")(w, r)
+			}),
+		)
+		var resp string
+		var err = client.Get(&resp, urlplugin.Path(path))
+		assert.Nil(t, err)
+		assert.Equal(t, "response", resp)
+	})
+	t.Run("Retry-After exceeds the caller deadline", func(t *testing.T) {
+		mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "120")
+			respondWith(http.StatusServiceUnavailable, "maintenance")(w, r)
+		})
+		var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var err = client.GetCtx(ctx, nil, urlplugin.Path(path))
+		assert.Equal(t, HTTPError{StatusCode: http.StatusServiceUnavailable, Message: "maintenance", Attempts: 1, RetryAfter: 2 * time.Minute}, withoutRequestDetails(err))
+	})
+	t.Run("Body read once is sent again", func(t *testing.T) {
+		var expectBody = func(status int) func(w http.ResponseWriter, r *http.Request) {
+			return func(w http.ResponseWriter, r *http.Request) {
+				var content, _ = io.ReadAll(r.Body)
+				assert.Equal(t, "content", string(content))
+				assert.Equal(t, int64(len(content)), r.ContentLength)
+				respondWith(status, "")(w, r)
+			}
+		}
+		gomock.InOrder(
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.StatusBadGateway)),
+			mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(expectBody(http.StatusOK)),
+		)
+		var err = client.Put(urlplugin.Path(path), body.Reader(io.MultiReader(strings.NewReader("content"))))
+		assert.Nil(t, err)
+	})
+	t.Run("Network errors are retried", func(t *testing.T) {
+		var attempts int
+		var failingClient, _ = NewWithOptions("http://localhost:19766", time.Minute, WithRetryPolicy(testRetryPolicy()),
+			WithRequestUpdaters(countRequests(&attempts)))
+		var err = failingClient.Get(nil, urlplugin.Path(path))
+		assert.Contains(t, err.Error(), MsgErrCannotObtain+"."+PrmResponse)
+		assert.Equal(t, 3, attempts)
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("Zero value disables retries", func(t *testing.T) {
+		var policy RetryPolicy
+		assert.Equal(t, 1, policy.maxAttempts(http.MethodGet))
+		assert.False(t, policy.retryStatus(http.StatusBadGateway))
+		assert.False(t, policy.retryError(io.EOF))
+	})
+	t.Run("Only configured methods are retried", func(t *testing.T) {
+		var policy = DefaultRetryPolicy()
+		assert.Equal(t, 3, policy.maxAttempts(http.MethodGet))
+		assert.Equal(t, 1, policy.maxAttempts(http.MethodPost))
+		assert.Equal(t, 1, policy.maxAttempts(http.MethodPatch))
+	})
+	t.Run("Exponential backoff", func(t *testing.T) {
+		var policy = RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+		assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+		assert.Equal(t, 200*time.Millisecond, policy.backoff(2))
+		assert.Equal(t, 400*time.Millisecond, policy.backoff(3))
+		assert.Equal(t, time.Second, policy.backoff(10))
+	})
+	t.Run("Jitter", func(t *testing.T) {
+		var policy = RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 2, Jitter: 0.5}
+		for range 20 {
+			var delay = policy.backoff(2)
+			assert.True(t, delay >= 100*time.Millisecond && delay <= 200*time.Millisecond)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	var now = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	var delay, ok = parseRetryAfter("30", now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, delay)
+
+	delay, ok = parseRetryAfter("Fri, 01 Mar 2024 12:01:30 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, delay)
+
+	delay, ok = parseRetryAfter("Fri, 01 Mar 2024 11:00:00 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), delay)
+
+	for _, invalid := range []string{"", "-5", "soon"} {
+		_, ok = parseRetryAfter(invalid, now)
+		assert.False(t, ok)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	var opErr = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	assert.True(t, IsRetryableError(&url.Error{Op: "Get", URL: "http://localhost", Err: opErr}))
+	assert.True(t, IsRetryableError(&url.Error{Op: "Get", URL: "http://localhost", Err: io.ErrUnexpectedEOF}))
+	assert.True(t, IsRetryableError(syscall.ECONNRESET))
+	assert.False(t, IsRetryableError(&url.Error{Op: "Get", URL: "http://localhost", Err: context.Canceled}))
+	assert.False(t, IsRetryableError(&url.Error{Op: "Get", URL: "http://localhost", Err: errors.New("unsupported protocol scheme")}))
+
+	var dnsErr = func(err *net.DNSError) error {
+		return &url.Error{Op: "Get", URL: "http://unknown.host", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+	}
+	assert.False(t, IsRetryableError(dnsErr(&net.DNSError{Err: "no such host", Name: "unknown.host", IsNotFound: true})))
+	assert.True(t, IsRetryableError(dnsErr(&net.DNSError{Err: "server misbehaving", Name: "unknown.host", IsTemporary: true})))
+	assert.True(t, IsRetryableError(dnsErr(&net.DNSError{Err: "i/o timeout", Name: "unknown.host", IsTimeout: true})))
+	assert.True(t, IsRetryableError(&url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}}))
+	assert.False(t, IsRetryableError(&url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.EACCES}}))
+}
+
+// timeoutError is a net.Error reporting a timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryableStatus(t *testing.T) {
+	assert.True(t, IsRetryableStatus(http.StatusTooManyRequests))
+	assert.True(t, IsRetryableStatus(http.StatusBadGateway))
+	assert.True(t, IsRetryableStatus(http.StatusServiceUnavailable))
+	assert.True(t, IsRetryableStatus(http.StatusGatewayTimeout))
+	assert.False(t, IsRetryableStatus(http.StatusInternalServerError))
+	assert.False(t, IsRetryableStatus(http.StatusNotFound))
+}
+
+func countRequests(counter *int) func(*gentleman.Request) (*gentleman.Request, error) {
+	return func(r *gentleman.Request) (*gentleman.Request, error) {
+		*counter++
+		return r, nil
+	}
+}
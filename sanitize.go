@@ -0,0 +1,49 @@
+package httpclient
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveQueryParameters are the (lower case) names of the query parameters whose value is redacted by SanitizeURL
+var sensitiveQueryParameters = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"token":         true,
+	"code":          true,
+	"client_secret": true,
+	"secret":        true,
+	"password":      true,
+	"api_key":       true,
+	"apikey":        true,
+	"key":           true,
+	"signature":     true,
+	"sig":           true,
+}
+
+// SanitizeURL returns u as a string where the password of the user info and the values of the sensitive query parameters are redacted
+func SanitizeURL(u *url.URL) string {
+	var sanitized = *u
+	if _, hasPassword := u.User.Password(); hasPassword {
+		sanitized.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	if u.RawQuery != "" {
+		var query = u.Query()
+		var redact = false
+		for name, values := range query {
+			if sensitiveQueryParameters[strings.ToLower(name)] {
+				for i := range values {
+					values[i] = redacted
+				}
+				redact = true
+			}
+		}
+		if redact {
+			sanitized.RawQuery = query.Encode()
+		}
+	}
+	return sanitized.String()
+}